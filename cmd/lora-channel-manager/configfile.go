@@ -0,0 +1,1260 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/brocaar/lora-channel-manager/manager"
+)
+
+// fileConfig contains the structure of the TOML configuration file.
+type fileConfig struct {
+	General struct {
+		GatewayMAC         string `toml:"gw_mac"`
+		GatewayMACSource   string `toml:"gw_mac_source"`
+		GatewayMACFile     string `toml:"gw_mac_config_file"`
+		GatewayMACIface    string `toml:"gw_mac_interface"`
+		GatewayMACCommand  string `toml:"gw_mac_command"`
+		BaseConfigFile     string `toml:"base_config_file"`
+		OutputConfigFile   string `toml:"output_config_file"`
+		PFRestartCommand   string `toml:"pf_restart_command"`
+		ConfigPollInterval string `toml:"config_poll_interval"`
+		Band               string `toml:"band"`
+		DwellTime400ms     bool   `toml:"dwell_time_400ms"`
+	} `toml:"general"`
+
+	GatewayServer struct {
+		Server            string `toml:"server"`
+		ServerSRV         string `toml:"server_srv"`
+		Balancing         string `toml:"balancing"`
+		RetryInterval     string `toml:"retry_interval"`
+		CACert            string `toml:"ca_cert"`
+		CACertSystemRoots bool   `toml:"ca_cert_system_roots"`
+		TLSCert           string `toml:"tls_cert"`
+		TLSKey            string `toml:"tls_key"`
+		TLSServerName     string `toml:"tls_server_name"`
+		TLSMinVersion     string `toml:"tls_min_version"`
+		JWTToken          string `toml:"jwt_token"`
+		JWTTokenFile      string `toml:"jwt_token_file"`
+		JWTAllowInsecure  bool   `toml:"jwt_allow_insecure"`
+	} `toml:"gateway_server"`
+
+	TX fileConfigTX `toml:"tx"`
+
+	PacketForwarder fileConfigForwarder `toml:"packet_forwarder"`
+
+	Beacon fileConfigBeacon `toml:"beacon"`
+
+	Location fileConfigLocation `toml:"location"`
+
+	LBT fileConfigLBT `toml:"lbt"`
+
+	Radio0 fileConfigRadio `toml:"radio_0"`
+
+	Radio1 fileConfigRadio `toml:"radio_1"`
+
+	Reload fileConfigReload `toml:"reload"`
+
+	Maintenance struct {
+		Windows  []string `toml:"windows"`
+		Timezone string   `toml:"timezone"`
+	} `toml:"maintenance"`
+
+	Dampening struct {
+		MinRestartInterval string `toml:"min_restart_interval"`
+		MaxRestartsPerHour int    `toml:"max_restarts_per_hour"`
+		SettlePolls        int    `toml:"settle_polls"`
+	} `toml:"dampening"`
+
+	Report struct {
+		URL     string `toml:"url"`
+		Token   string `toml:"token"`
+		GWJWT   bool   `toml:"gw_jwt"`
+		Timeout string `toml:"timeout"`
+	} `toml:"report"`
+
+	Hooks struct {
+		Command string   `toml:"command"`
+		URL     string   `toml:"url"`
+		Token   string   `toml:"token"`
+		Events  []string `toml:"events"`
+		Timeout string   `toml:"timeout"`
+	} `toml:"hooks"`
+
+	Metrics struct {
+		Bind string `toml:"bind"`
+	} `toml:"metrics"`
+
+	Gateways []fileConfigGateway `toml:"gateways"`
+}
+
+// fileConfigForwarder contains the managed gateway_conf settings of the
+// configuration file.
+type fileConfigForwarder struct {
+	ServerAddress      string `toml:"server_address"`
+	ServPortUp         int    `toml:"serv_port_up"`
+	ServPortDown       int    `toml:"serv_port_down"`
+	KeepaliveInterval  int    `toml:"keepalive_interval"`
+	StatInterval       int    `toml:"stat_interval"`
+	PushTimeoutMS      int    `toml:"push_timeout_ms"`
+	ForwardCRCValid    *bool  `toml:"forward_crc_valid"`
+	ForwardCRCError    *bool  `toml:"forward_crc_error"`
+	ForwardCRCDisabled *bool  `toml:"forward_crc_disabled"`
+}
+
+// forwarderConfig returns the manager packet-forwarder configuration. When
+// none of the options is set, nil is returned so that the gateway_conf of
+// the base configuration file is kept.
+func (f fileConfigForwarder) forwarderConfig() *manager.ForwarderConfig {
+	if f == (fileConfigForwarder{}) {
+		return nil
+	}
+
+	return &manager.ForwarderConfig{
+		ServerAddress:      f.ServerAddress,
+		ServPortUp:         f.ServPortUp,
+		ServPortDown:       f.ServPortDown,
+		KeepaliveInterval:  f.KeepaliveInterval,
+		StatInterval:       f.StatInterval,
+		PushTimeoutMS:      f.PushTimeoutMS,
+		ForwardCRCValid:    f.ForwardCRCValid,
+		ForwardCRCError:    f.ForwardCRCError,
+		ForwardCRCDisabled: f.ForwardCRCDisabled,
+	}
+}
+
+// fileConfigBeacon contains the Class-B beacon configuration of the
+// configuration file.
+type fileConfigBeacon struct {
+	Enable       bool `toml:"enable"`
+	Period       int  `toml:"period"`
+	Freq         int  `toml:"freq"`
+	FreqNb       int  `toml:"freq_nb"`
+	FreqStep     int  `toml:"freq_step"`
+	SpreadFactor int  `toml:"spread_factor"`
+	Bandwidth    int  `toml:"bandwidth"`
+	Power        int  `toml:"power"`
+	InfoDesc     int  `toml:"info_desc"`
+}
+
+// beaconConfig returns the manager beacon configuration. When none of the
+// options is set, nil is returned so that the beacon configuration of the
+// base configuration file is kept. When the beacon is not enabled, but
+// other options are set, the beacon is disabled.
+func (f fileConfigBeacon) beaconConfig() *manager.BeaconConfig {
+	if f == (fileConfigBeacon{}) {
+		return nil
+	}
+
+	c := manager.BeaconConfig{
+		Period:       f.Period,
+		Freq:         f.Freq,
+		FreqNb:       f.FreqNb,
+		FreqStep:     f.FreqStep,
+		SpreadFactor: f.SpreadFactor,
+		Bandwidth:    f.Bandwidth,
+		Power:        f.Power,
+		InfoDesc:     f.InfoDesc,
+	}
+	if !f.Enable {
+		c.Period = 0
+	} else if c.Period == 0 {
+		c.Period = 128
+	}
+
+	return &c
+}
+
+// fileConfigLocation contains the GPS and location configuration of the
+// configuration file.
+type fileConfigLocation struct {
+	GPSTTYPath string `toml:"gps_tty_path"`
+	GPSDisable bool   `toml:"gps_disable"`
+	FakeGPS    bool   `toml:"fake_gps"`
+
+	// Latitude, Longitude and Altitude are nil when not set, as 0 is a
+	// valid coordinate.
+	Latitude  *float64 `toml:"latitude"`
+	Longitude *float64 `toml:"longitude"`
+	Altitude  *int     `toml:"altitude"`
+}
+
+// LatitudeValue, LongitudeValue and AltitudeValue return the location as
+// flag (and configuration file) value, blank when not set.
+func (f fileConfigLocation) LatitudeValue() string  { return formatOptionalFloat(f.Latitude) }
+func (f fileConfigLocation) LongitudeValue() string { return formatOptionalFloat(f.Longitude) }
+func (f fileConfigLocation) AltitudeValue() string {
+	if f.Altitude == nil {
+		return ""
+	}
+	return strconv.Itoa(*f.Altitude)
+}
+
+// formatOptionalFloat formats the given float (always including a decimal
+// point, so that it is decoded as TOML float). It returns blank when nil.
+func formatOptionalFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	s := strconv.FormatFloat(*f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// parseOptionalFloat parses the given float flag value. It returns nil when
+// the value is blank.
+func parseOptionalFloat(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// parseOptionalInt parses the given int flag value. It returns nil when
+// the value is blank.
+func parseOptionalInt(s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// locationFromFlags returns the location configuration from the given
+// flag values.
+func locationFromFlags(gpsTTYPath string, gpsDisable, fakeGPS bool, latitude, longitude, altitude string) (fileConfigLocation, error) {
+	f := fileConfigLocation{
+		GPSTTYPath: gpsTTYPath,
+		GPSDisable: gpsDisable,
+		FakeGPS:    fakeGPS,
+	}
+	var err error
+	if f.Latitude, err = parseOptionalFloat(latitude); err != nil {
+		return f, errors.Wrap(err, "invalid location-latitude")
+	}
+	if f.Longitude, err = parseOptionalFloat(longitude); err != nil {
+		return f, errors.Wrap(err, "invalid location-longitude")
+	}
+	if f.Altitude, err = parseOptionalInt(altitude); err != nil {
+		return f, errors.Wrap(err, "invalid location-altitude")
+	}
+	return f, nil
+}
+
+// locationConfig returns the manager location configuration. When none of
+// the options is set, nil is returned so that the GPS and location
+// configuration of the base configuration file is kept.
+func (f fileConfigLocation) locationConfig() *manager.LocationConfig {
+	if f == (fileConfigLocation{}) {
+		return nil
+	}
+
+	return &manager.LocationConfig{
+		GPSTTYPath: f.GPSTTYPath,
+		DisableGPS: f.GPSDisable,
+		FakeGPS:    f.FakeGPS,
+		Latitude:   f.Latitude,
+		Longitude:  f.Longitude,
+		Altitude:   f.Altitude,
+	}
+}
+
+// fileConfigLBT contains the listen-before-talk configuration of the
+// configuration file.
+type fileConfigLBT struct {
+	Enable     bool `toml:"enable"`
+	RSSITarget int  `toml:"rssi_target"`
+	ScanTime   int  `toml:"scan_time"`
+}
+
+// lbtConfig returns the manager LBT configuration. When none of the options
+// is set, nil is returned so that the LBT configuration of the base
+// configuration file is kept. When LBT is not enabled, but other options
+// are set, LBT is disabled.
+func (f fileConfigLBT) lbtConfig() *manager.LBTConfig {
+	if f == (fileConfigLBT{}) {
+		return nil
+	}
+
+	return &manager.LBTConfig{
+		Enable:     f.Enable,
+		RSSITarget: f.RSSITarget,
+		ScanTime:   f.ScanTime,
+	}
+}
+
+// fileConfigRadio contains the radio hardware configuration of the
+// configuration file.
+type fileConfigRadio struct {
+	Type    string `toml:"type"`
+	FreqMin int    `toml:"freq_min"`
+	FreqMax int    `toml:"freq_max"`
+}
+
+// radioConfig returns the manager radio hardware configuration.
+func (f fileConfigRadio) radioConfig() manager.RadioHardwareConfig {
+	return manager.RadioHardwareConfig{
+		Type:    f.Type,
+		FreqMin: f.FreqMin,
+		FreqMax: f.FreqMax,
+	}
+}
+
+// fileConfigReload contains the packet-forwarder reload configuration of
+// the configuration file.
+type fileConfigReload struct {
+	Strategy       string `toml:"strategy"`
+	PIDFile        string `toml:"pid_file"`
+	ControlSocket  string `toml:"control_socket"`
+	ControlCommand string `toml:"control_command"`
+}
+
+// reloader returns the manager reloader for the configured strategy. For
+// the restart strategy, nil is returned so that all changes restart the
+// packet-forwarder.
+func (f fileConfigReload) reloader() (manager.Reloader, error) {
+	switch f.Strategy {
+	case "", "restart":
+		return nil, nil
+	case "sighup":
+		if f.PIDFile == "" {
+			return nil, errors.New("the sighup strategy requires a pid file")
+		}
+		return manager.SignalReloader{PIDFile: f.PIDFile}, nil
+	case "socket":
+		if f.ControlSocket == "" {
+			return nil, errors.New("the socket strategy requires a control socket")
+		}
+		return manager.SocketReloader{Address: f.ControlSocket, Command: f.ControlCommand}, nil
+	default:
+		return nil, fmt.Errorf("invalid reload strategy: %s", f.Strategy)
+	}
+}
+
+// formatOptionalBool formats the given optional bool, returning an empty
+// string when not set.
+func formatOptionalBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+// parseOptionalBool parses the given optional bool, returning nil when
+// the given string is empty.
+func parseOptionalBool(s string) (*bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// fileConfigTX contains the TX configuration of the configuration file.
+type fileConfigTX struct {
+	Disable bool `toml:"disable"`
+
+	// Radio is nil when not set, -1 is handled as not set.
+	Radio       *int `toml:"radio"`
+	FreqMin     int  `toml:"freq_min"`
+	FreqMax     int  `toml:"freq_max"`
+	MaxEIRP     int  `toml:"max_eirp"`
+	AntennaGain int  `toml:"antenna_gain"`
+	CableLoss   int  `toml:"cable_loss"`
+}
+
+// txConfig returns the manager TX configuration. When none of the TX
+// options is set, nil is returned so that the TX configuration of the base
+// configuration file is kept. The radio TX settings are only managed when
+// disable, radio or the frequency range is set.
+func (f fileConfigTX) txConfig() *manager.TXConfig {
+	if f.Radio != nil && *f.Radio < 0 {
+		f.Radio = nil
+	}
+	if f == (fileConfigTX{}) {
+		return nil
+	}
+
+	tx := manager.TXConfig{
+		KeepRadios:  !f.Disable && f.Radio == nil && f.FreqMin == 0 && f.FreqMax == 0,
+		Enable:      !f.Disable,
+		FreqMin:     f.FreqMin,
+		FreqMax:     f.FreqMax,
+		MaxEIRP:     f.MaxEIRP,
+		AntennaGain: f.AntennaGain,
+		CableLoss:   f.CableLoss,
+	}
+	if f.Radio != nil {
+		tx.Radio = *f.Radio
+	}
+	return &tx
+}
+
+// RadioValue returns the tx radio as flag value (-1 when not set).
+func (f fileConfigTX) RadioValue() int {
+	if f.Radio == nil {
+		return -1
+	}
+	return *f.Radio
+}
+
+// txRadioFlag returns the tx radio flag value as pointer (nil when not
+// set).
+func txRadioFlag(radio int) *int {
+	if radio < 0 {
+		return nil
+	}
+	return &radio
+}
+
+// fileConfigGateway contains a gateway definition of the configuration file.
+type fileConfigGateway struct {
+	MAC              string `toml:"mac"`
+	BaseConfigFile   string `toml:"base_config_file"`
+	OutputConfigFile string `toml:"output_config_file"`
+	PFRestartCommand string `toml:"pf_restart_command"`
+
+	// TX overrides the [tx] configuration for this gateway.
+	TX *fileConfigTX `toml:"tx"`
+
+	// PacketForwarder overrides the [packet_forwarder] configuration for
+	// this gateway.
+	PacketForwarder *fileConfigForwarder `toml:"packet_forwarder"`
+
+	// Beacon overrides the [beacon] configuration for this gateway.
+	Beacon *fileConfigBeacon `toml:"beacon"`
+
+	// Location overrides the [location] configuration for this gateway.
+	Location *fileConfigLocation `toml:"location"`
+
+	// LBT overrides the [lbt] configuration for this gateway.
+	LBT *fileConfigLBT `toml:"lbt"`
+
+	// Radio0 and Radio1 override the [radio_0] and [radio_1] configuration
+	// for this gateway.
+	Radio0 *fileConfigRadio `toml:"radio_0"`
+	Radio1 *fileConfigRadio `toml:"radio_1"`
+
+	// Reload overrides the [reload] configuration for this gateway.
+	Reload *fileConfigReload `toml:"reload"`
+}
+
+// fileConf contains the loaded configuration file.
+var fileConf fileConfig
+
+// flagValues returns the configuration-file values, keyed by the name of
+// the cli flag they map to.
+func (f fileConfig) flagValues() map[string]string {
+	return map[string]string{
+		"gw-mac":                          f.General.GatewayMAC,
+		"gw-mac-source":                   f.General.GatewayMACSource,
+		"gw-mac-config-file":              f.General.GatewayMACFile,
+		"gw-mac-interface":                f.General.GatewayMACIface,
+		"gw-mac-command":                  f.General.GatewayMACCommand,
+		"base-config-file":                f.General.BaseConfigFile,
+		"output-config-file":              f.General.OutputConfigFile,
+		"pf-restart-command":              f.General.PFRestartCommand,
+		"pf-reload-strategy":              f.Reload.Strategy,
+		"pf-reload-pid-file":              f.Reload.PIDFile,
+		"pf-reload-control-socket":        f.Reload.ControlSocket,
+		"pf-reload-control-command":       f.Reload.ControlCommand,
+		"config-poll-interval":            f.General.ConfigPollInterval,
+		"band":                            f.General.Band,
+		"dwell-time-400ms":                strconv.FormatBool(f.General.DwellTime400ms),
+		"gw-server":                       f.GatewayServer.Server,
+		"gw-server-srv":                   f.GatewayServer.ServerSRV,
+		"gw-server-balancing":             f.GatewayServer.Balancing,
+		"gw-server-retry-interval":        f.GatewayServer.RetryInterval,
+		"gw-client-ca-cert":               f.GatewayServer.CACert,
+		"gw-client-tls-cert":              f.GatewayServer.TLSCert,
+		"gw-client-tls-key":               f.GatewayServer.TLSKey,
+		"gw-client-ca-cert-system-roots":  strconv.FormatBool(f.GatewayServer.CACertSystemRoots),
+		"gw-client-tls-server-name":       f.GatewayServer.TLSServerName,
+		"gw-client-tls-min-version":       f.GatewayServer.TLSMinVersion,
+		"gw-client-jwt-token":             f.GatewayServer.JWTToken,
+		"gw-client-jwt-token-file":        f.GatewayServer.JWTTokenFile,
+		"gw-client-jwt-allow-insecure":    strconv.FormatBool(f.GatewayServer.JWTAllowInsecure),
+		"tx-disable":                      strconv.FormatBool(f.TX.Disable),
+		"tx-radio":                        strconv.Itoa(f.TX.RadioValue()),
+		"tx-freq-min":                     strconv.Itoa(f.TX.FreqMin),
+		"tx-freq-max":                     strconv.Itoa(f.TX.FreqMax),
+		"tx-max-eirp":                     strconv.Itoa(f.TX.MaxEIRP),
+		"tx-antenna-gain":                 strconv.Itoa(f.TX.AntennaGain),
+		"tx-cable-loss":                   strconv.Itoa(f.TX.CableLoss),
+		"pf-server-address":               f.PacketForwarder.ServerAddress,
+		"pf-serv-port-up":                 strconv.Itoa(f.PacketForwarder.ServPortUp),
+		"pf-serv-port-down":               strconv.Itoa(f.PacketForwarder.ServPortDown),
+		"pf-keepalive-interval":           strconv.Itoa(f.PacketForwarder.KeepaliveInterval),
+		"pf-stat-interval":                strconv.Itoa(f.PacketForwarder.StatInterval),
+		"pf-push-timeout-ms":              strconv.Itoa(f.PacketForwarder.PushTimeoutMS),
+		"pf-forward-crc-valid":            formatOptionalBool(f.PacketForwarder.ForwardCRCValid),
+		"pf-forward-crc-error":            formatOptionalBool(f.PacketForwarder.ForwardCRCError),
+		"pf-forward-crc-disabled":         formatOptionalBool(f.PacketForwarder.ForwardCRCDisabled),
+		"beacon-enable":                   strconv.FormatBool(f.Beacon.Enable),
+		"beacon-period":                   strconv.Itoa(f.Beacon.Period),
+		"beacon-freq":                     strconv.Itoa(f.Beacon.Freq),
+		"beacon-freq-nb":                  strconv.Itoa(f.Beacon.FreqNb),
+		"beacon-freq-step":                strconv.Itoa(f.Beacon.FreqStep),
+		"beacon-spread-factor":            strconv.Itoa(f.Beacon.SpreadFactor),
+		"beacon-bandwidth":                strconv.Itoa(f.Beacon.Bandwidth),
+		"beacon-power":                    strconv.Itoa(f.Beacon.Power),
+		"beacon-info-desc":                strconv.Itoa(f.Beacon.InfoDesc),
+		"location-gps-tty-path":           f.Location.GPSTTYPath,
+		"location-gps-disable":            strconv.FormatBool(f.Location.GPSDisable),
+		"location-fake-gps":               strconv.FormatBool(f.Location.FakeGPS),
+		"location-latitude":               f.Location.LatitudeValue(),
+		"location-longitude":              f.Location.LongitudeValue(),
+		"location-altitude":               f.Location.AltitudeValue(),
+		"lbt-enable":                      strconv.FormatBool(f.LBT.Enable),
+		"lbt-rssi-target":                 strconv.Itoa(f.LBT.RSSITarget),
+		"lbt-scan-time":                   strconv.Itoa(f.LBT.ScanTime),
+		"radio-0-type":                    f.Radio0.Type,
+		"radio-0-freq-min":                strconv.Itoa(f.Radio0.FreqMin),
+		"radio-0-freq-max":                strconv.Itoa(f.Radio0.FreqMax),
+		"radio-1-type":                    f.Radio1.Type,
+		"radio-1-freq-min":                strconv.Itoa(f.Radio1.FreqMin),
+		"radio-1-freq-max":                strconv.Itoa(f.Radio1.FreqMax),
+		"maintenance-windows":             strings.Join(f.Maintenance.Windows, ";"),
+		"maintenance-timezone":            f.Maintenance.Timezone,
+		"dampening-min-restart-interval":  f.Dampening.MinRestartInterval,
+		"dampening-max-restarts-per-hour": strconv.Itoa(f.Dampening.MaxRestartsPerHour),
+		"dampening-settle-polls":          strconv.Itoa(f.Dampening.SettlePolls),
+		"report-url":                      f.Report.URL,
+		"report-token":                    f.Report.Token,
+		"report-gw-jwt":                   strconv.FormatBool(f.Report.GWJWT),
+		"report-timeout":                  f.Report.Timeout,
+		"hook-command":                    f.Hooks.Command,
+		"hook-url":                        f.Hooks.URL,
+		"hook-token":                      f.Hooks.Token,
+		"hook-events":                     strings.Join(f.Hooks.Events, ","),
+		"hook-timeout":                    f.Hooks.Timeout,
+		"metrics-bind":                    f.Metrics.Bind,
+	}
+}
+
+// fileConfigFromContext returns the fileConfig matching the current
+// (resolved) cli flag values.
+func fileConfigFromContext(c *cli.Context) fileConfig {
+	var f fileConfig
+	f.General.GatewayMAC = c.GlobalString("gw-mac")
+	f.General.GatewayMACSource = c.GlobalString("gw-mac-source")
+	f.General.GatewayMACFile = c.GlobalString("gw-mac-config-file")
+	f.General.GatewayMACIface = c.GlobalString("gw-mac-interface")
+	f.General.GatewayMACCommand = c.GlobalString("gw-mac-command")
+	f.General.BaseConfigFile = c.GlobalString("base-config-file")
+	f.General.OutputConfigFile = c.GlobalString("output-config-file")
+	f.General.PFRestartCommand = c.GlobalString("pf-restart-command")
+	f.General.ConfigPollInterval = c.GlobalDuration("config-poll-interval").String()
+	f.General.Band = c.GlobalString("band")
+	f.General.DwellTime400ms = c.GlobalBool("dwell-time-400ms")
+	f.GatewayServer.Server = c.GlobalString("gw-server")
+	f.GatewayServer.ServerSRV = c.GlobalString("gw-server-srv")
+	f.GatewayServer.Balancing = c.GlobalString("gw-server-balancing")
+	f.GatewayServer.RetryInterval = c.GlobalDuration("gw-server-retry-interval").String()
+	f.GatewayServer.CACert = c.GlobalString("gw-client-ca-cert")
+	f.GatewayServer.TLSCert = c.GlobalString("gw-client-tls-cert")
+	f.GatewayServer.TLSKey = c.GlobalString("gw-client-tls-key")
+	f.GatewayServer.CACertSystemRoots = c.GlobalBool("gw-client-ca-cert-system-roots")
+	f.GatewayServer.TLSServerName = c.GlobalString("gw-client-tls-server-name")
+	f.GatewayServer.TLSMinVersion = c.GlobalString("gw-client-tls-min-version")
+	f.GatewayServer.JWTToken = c.GlobalString("gw-client-jwt-token")
+	f.GatewayServer.JWTTokenFile = c.GlobalString("gw-client-jwt-token-file")
+	f.GatewayServer.JWTAllowInsecure = c.GlobalBool("gw-client-jwt-allow-insecure")
+	f.TX = fileConfigTX{
+		Disable:     c.GlobalBool("tx-disable"),
+		Radio:       txRadioFlag(c.GlobalInt("tx-radio")),
+		FreqMin:     c.GlobalInt("tx-freq-min"),
+		FreqMax:     c.GlobalInt("tx-freq-max"),
+		MaxEIRP:     c.GlobalInt("tx-max-eirp"),
+		AntennaGain: c.GlobalInt("tx-antenna-gain"),
+		CableLoss:   c.GlobalInt("tx-cable-loss"),
+	}
+	f.PacketForwarder = fileConfigForwarder{
+		ServerAddress:     c.GlobalString("pf-server-address"),
+		ServPortUp:        c.GlobalInt("pf-serv-port-up"),
+		ServPortDown:      c.GlobalInt("pf-serv-port-down"),
+		KeepaliveInterval: c.GlobalInt("pf-keepalive-interval"),
+		StatInterval:      c.GlobalInt("pf-stat-interval"),
+		PushTimeoutMS:     c.GlobalInt("pf-push-timeout-ms"),
+	}
+	f.PacketForwarder.ForwardCRCValid, _ = parseOptionalBool(c.GlobalString("pf-forward-crc-valid"))
+	f.PacketForwarder.ForwardCRCError, _ = parseOptionalBool(c.GlobalString("pf-forward-crc-error"))
+	f.PacketForwarder.ForwardCRCDisabled, _ = parseOptionalBool(c.GlobalString("pf-forward-crc-disabled"))
+	f.Beacon = fileConfigBeacon{
+		Enable:       c.GlobalBool("beacon-enable"),
+		Period:       c.GlobalInt("beacon-period"),
+		Freq:         c.GlobalInt("beacon-freq"),
+		FreqNb:       c.GlobalInt("beacon-freq-nb"),
+		FreqStep:     c.GlobalInt("beacon-freq-step"),
+		SpreadFactor: c.GlobalInt("beacon-spread-factor"),
+		Bandwidth:    c.GlobalInt("beacon-bandwidth"),
+		Power:        c.GlobalInt("beacon-power"),
+		InfoDesc:     c.GlobalInt("beacon-info-desc"),
+	}
+	// invalid location values are left out, these are rejected on start
+	f.Location, _ = locationFromFlags(
+		c.GlobalString("location-gps-tty-path"),
+		c.GlobalBool("location-gps-disable"),
+		c.GlobalBool("location-fake-gps"),
+		c.GlobalString("location-latitude"),
+		c.GlobalString("location-longitude"),
+		c.GlobalString("location-altitude"),
+	)
+	f.LBT = fileConfigLBT{
+		Enable:     c.GlobalBool("lbt-enable"),
+		RSSITarget: c.GlobalInt("lbt-rssi-target"),
+		ScanTime:   c.GlobalInt("lbt-scan-time"),
+	}
+	f.Radio0 = fileConfigRadio{
+		Type:    c.GlobalString("radio-0-type"),
+		FreqMin: c.GlobalInt("radio-0-freq-min"),
+		FreqMax: c.GlobalInt("radio-0-freq-max"),
+	}
+	f.Radio1 = fileConfigRadio{
+		Type:    c.GlobalString("radio-1-type"),
+		FreqMin: c.GlobalInt("radio-1-freq-min"),
+		FreqMax: c.GlobalInt("radio-1-freq-max"),
+	}
+	f.Reload = fileConfigReload{
+		Strategy:       c.GlobalString("pf-reload-strategy"),
+		PIDFile:        c.GlobalString("pf-reload-pid-file"),
+		ControlSocket:  c.GlobalString("pf-reload-control-socket"),
+		ControlCommand: c.GlobalString("pf-reload-control-command"),
+	}
+	for _, w := range strings.Split(c.GlobalString("maintenance-windows"), ";") {
+		if strings.TrimSpace(w) != "" {
+			f.Maintenance.Windows = append(f.Maintenance.Windows, w)
+		}
+	}
+	f.Maintenance.Timezone = c.GlobalString("maintenance-timezone")
+	f.Dampening.MinRestartInterval = c.GlobalDuration("dampening-min-restart-interval").String()
+	f.Dampening.MaxRestartsPerHour = c.GlobalInt("dampening-max-restarts-per-hour")
+	f.Dampening.SettlePolls = c.GlobalInt("dampening-settle-polls")
+	f.Report.URL = c.GlobalString("report-url")
+	f.Report.Token = c.GlobalString("report-token")
+	f.Report.GWJWT = c.GlobalBool("report-gw-jwt")
+	f.Report.Timeout = c.GlobalDuration("report-timeout").String()
+	f.Hooks.Command = c.GlobalString("hook-command")
+	f.Hooks.URL = c.GlobalString("hook-url")
+	f.Hooks.Token = c.GlobalString("hook-token")
+	for _, e := range strings.Split(c.GlobalString("hook-events"), ",") {
+		if strings.TrimSpace(e) != "" {
+			f.Hooks.Events = append(f.Hooks.Events, strings.TrimSpace(e))
+		}
+	}
+	f.Hooks.Timeout = c.GlobalDuration("hook-timeout").String()
+	f.Metrics.Bind = c.GlobalString("metrics-bind")
+	f.Gateways = fileConf.Gateways
+	return f
+}
+
+// loadConfigFile loads the configuration file given by --config (if set)
+// and uses its values for all flags that were not set by cli argument or
+// environment variable. The resulting precedence is:
+// flags > environment variables > configuration file > flag defaults.
+// Note that empty environment variables are ignored, as the packaged
+// defaults file exports all options, including the ones left blank.
+func loadConfigFile(c *cli.Context) error {
+	path := c.String("config")
+	if path == "" {
+		return nil
+	}
+
+	if _, err := toml.DecodeFile(path, &fileConf); err != nil {
+		return errors.Wrap(err, "decode config file error")
+	}
+
+	for name, value := range fileConf.flagValues() {
+		if value == "" || (c.IsSet(name) && c.String(name) != "") {
+			continue
+		}
+		if err := c.Set(name, value); err != nil {
+			return errors.Wrapf(err, "set %s from config file error", name)
+		}
+	}
+
+	log.WithField("path", path).Info("configuration file loaded")
+	return nil
+}
+
+const configTemplate = `[general]
+# MAC address of the gateway (HEX encoded, e.g. 0102030405060708).
+#
+# This can be left blank when the gateway MAC is discovered (see
+# gw_mac_source). When both are set, LoRa Channel Manager refuses to start
+# when they don't match.
+gw_mac="{{ .General.GatewayMAC }}"
+
+# Gateway MAC discovery source (optional).
+#
+# Valid options are:
+#   * config-file: read the gateway_ID from gw_mac_config_file
+#   * interface:   derive it from the MAC of gw_mac_interface (FFFE insertion)
+#   * command:     use the output of gw_mac_command
+gw_mac_source="{{ .General.GatewayMACSource }}"
+
+# Packet-forwarder configuration file containing the gateway_ID
+# (config-file source, defaults to base_config_file).
+gw_mac_config_file="{{ .General.GatewayMACFile }}"
+
+# Network interface to derive the gateway MAC from (interface source).
+gw_mac_interface="{{ .General.GatewayMACIface }}"
+
+# Command printing the gateway MAC (command source).
+gw_mac_command="{{ .General.GatewayMACCommand }}"
+
+# Path to the base configuration file.
+#
+# This file contains all the packet-forwarder configuration. The radio_,
+# chan_ and gateway_ID keys will be updated by LoRa Channel Manager.
+base_config_file="{{ .General.BaseConfigFile }}"
+
+# Path to the output configuration file.
+#
+# Note: this file will be overwritten on each configuration update!
+output_config_file="{{ .General.OutputConfigFile }}"
+
+# Command to restart the packet-forwarder.
+#
+# This command is executed each time a new configuration has been written
+# to the output configuration file.
+pf_restart_command="{{ .General.PFRestartCommand }}"
+
+# Interval between polling new configuration.
+config_poll_interval="{{ .General.ConfigPollInterval }}"
+
+# LoRaWAN band of the gateway (optional).
+#
+# Valid options are AS_923, AU_915_928, CN_470_510, CN_779_787, EU_433,
+# EU_863_870, IN_865_867, KR_920_923 and US_902_928. When set, the max.
+# EIRP of the band is used when [tx] max_eirp is not set and the [beacon]
+# settings default to the beacon settings of the band. The planned channels
+# are annotated with the sub-band, duty-cycle and dwell time of the band
+# and a warning is logged when the channel-plan does not respect these.
+band="{{ .General.Band }}"
+
+# Dwell time limitation (AS_923 only).
+#
+# Set this to true when the 400ms dwell time limitation applies (e.g. in
+# Japan).
+dwell_time_400ms={{ .General.DwellTime400ms }}
+
+
+# Gateway API server (exposed by LoRa Server).
+[gateway_server]
+# hostname:port of the gateway API server.
+#
+# For multiple servers, use a comma separated list
+# (e.g. "ns1.example.com:8002,ns2.example.com:8002").
+server="{{ .GatewayServer.Server }}"
+
+# DNS SRV record to lookup the gateway API servers (optional).
+#
+# When set, this takes precedence over server. The servers are ordered
+# by the SRV priority and weight.
+server_srv="{{ .GatewayServer.ServerSRV }}"
+
+# Balancing over multiple gateway API servers.
+#
+# Valid options are:
+#   * round-robin: distribute the requests over the healthy servers
+#   * failover:    always use the first healthy server
+balancing="{{ .GatewayServer.Balancing }}"
+
+# Interval after which a failing gateway API server is retried.
+retry_interval="{{ .GatewayServer.RetryInterval }}"
+
+# CA certificate used by the gateway-server client (optional).
+ca_cert="{{ .GatewayServer.CACert }}"
+
+# Use the system root CAs in addition to the CA certificate.
+ca_cert_system_roots={{ .GatewayServer.CACertSystemRoots }}
+
+# TLS certificate used by the gateway-server client (optional).
+#
+# The certificate and key are reloaded when modified, so that short-lived
+# client certificates can be rotated without restarting.
+tls_cert="{{ .GatewayServer.TLSCert }}"
+
+# TLS key used by the gateway-server client (optional).
+tls_key="{{ .GatewayServer.TLSKey }}"
+
+# Server name used to verify the gateway-server certificate (optional).
+#
+# Use this when connecting to the gateway API server by IP address.
+tls_server_name="{{ .GatewayServer.TLSServerName }}"
+
+# Minimum TLS version (1.0, 1.1 or 1.2).
+tls_min_version="{{ .GatewayServer.TLSMinVersion }}"
+
+# JWT token used by the gateway-server client for authentication
+# (issued by LoRa Server).
+jwt_token="{{ .GatewayServer.JWTToken }}"
+
+# File containing the JWT token (optional).
+#
+# When set, this takes precedence over jwt_token. The file is re-read
+# each time it has been modified, so that the token can be rotated without
+# restarting LoRa Channel Manager.
+jwt_token_file="{{ .GatewayServer.JWTTokenFile }}"
+
+# Allow sending the JWT token over an insecure (non-TLS) connection.
+#
+# By default, the JWT token is only sent when the connection to the
+# gateway API server is secured using TLS.
+jwt_allow_insecure={{ .GatewayServer.JWTAllowInsecure }}
+
+
+# TX configuration (optional).
+#
+# By default, the TX configuration (tx_enable, tx_freq_min, tx_freq_max and
+# tx_lut) of the base configuration file is kept. When one of the options
+# below is set, the TX configuration is managed by LoRa Channel Manager,
+# e.g. when a gateway is moved between regions. The radio TX settings
+# (tx_enable, tx_freq_min and tx_freq_max) are only managed when disable,
+# radio or the TX frequency range is set.
+[tx]
+# Disable TX on all radios (e.g. for receive-only gateways).
+disable={{ .TX.Disable }}
+
+# Radio used for TX (0 or 1).
+#
+# When left -1 (and disable and the TX frequency range are not set), the
+# tx_enable of the base configuration file is kept.
+radio={{ .TX.RadioValue }}
+
+# Allowed TX frequency range (Hz).
+#
+# When left 0, the tx_freq_min and tx_freq_max of the base configuration
+# file are kept.
+freq_min={{ .TX.FreqMin }}
+freq_max={{ .TX.FreqMax }}
+
+# Max. TX power (EIRP, dBm).
+#
+# When set, the (board specific calibrated) tx_lut entries (SX1302:
+# tx_gain_lut of the TX radio) of the base configuration file exceeding this
+# power are clamped to the highest entry within this power, taking the
+# antenna gain and cable loss into account. When left 0, the max. EIRP of
+# the band is used or when no band is set, the tx_lut of the base
+# configuration file is kept.
+max_eirp={{ .TX.MaxEIRP }}
+
+# Antenna gain (dBi) and cable loss (dB).
+#
+# When set, the antenna_gain of the base configuration file is replaced by
+# the antenna gain minus the cable loss.
+antenna_gain={{ .TX.AntennaGain }}
+cable_loss={{ .TX.CableLoss }}
+
+
+# Packet-forwarder settings (optional).
+#
+# By default, the gateway_conf of the base configuration file is kept
+# (except for the gateway_ID). The settings below that are set, replace the
+# corresponding gateway_conf settings, e.g. to migrate all gateways to a
+# new LoRa Gateway Bridge. Settings left blank (or 0) are kept.
+[packet_forwarder]
+# Hostname or IP of the packet-forwarder backend (e.g. LoRa Gateway Bridge).
+server_address="{{ .PacketForwarder.ServerAddress }}"
+
+# Uplink and downlink UDP ports.
+serv_port_up={{ .PacketForwarder.ServPortUp }}
+serv_port_down={{ .PacketForwarder.ServPortDown }}
+
+# Keepalive (PULL_DATA) and statistics interval (seconds).
+keepalive_interval={{ .PacketForwarder.KeepaliveInterval }}
+stat_interval={{ .PacketForwarder.StatInterval }}
+
+# PUSH_DATA ack timeout (milliseconds).
+push_timeout_ms={{ .PacketForwarder.PushTimeoutMS }}
+
+# Forward packets with a valid CRC, a CRC error or without CRC.
+{{ with .PacketForwarder.ForwardCRCValid }}forward_crc_valid={{ . }}{{ else }}# forward_crc_valid=true{{ end }}
+{{ with .PacketForwarder.ForwardCRCError }}forward_crc_error={{ . }}{{ else }}# forward_crc_error=false{{ end }}
+{{ with .PacketForwarder.ForwardCRCDisabled }}forward_crc_disabled={{ . }}{{ else }}# forward_crc_disabled=false{{ end }}
+
+
+# Class-B beacon (optional).
+#
+# By default, the beacon configuration of the base configuration file is
+# kept. When enabled, the beacon_* settings of the gateway_conf are set.
+# The options left 0 default to the beacon settings of the band (see
+# [general] band). When not enabled but one of the other options is set,
+# the beacon is disabled.
+#
+# Note: the beacon requires a GPS (time) reference.
+[beacon]
+# Enable the Class-B beacon.
+enable={{ .Beacon.Enable }}
+
+# Beacon period (seconds, defaults to 128).
+period={{ .Beacon.Period }}
+
+# Beacon frequency (Hz).
+#
+# In case of frequency hopping (e.g. US_902_928), this is the frequency
+# of the first beacon channel.
+freq={{ .Beacon.Freq }}
+
+# Number of beacon channels and the step between these channels (Hz), in
+# case of frequency hopping.
+freq_nb={{ .Beacon.FreqNb }}
+freq_step={{ .Beacon.FreqStep }}
+
+# Beacon spread-factor and bandwidth (Hz).
+spread_factor={{ .Beacon.SpreadFactor }}
+bandwidth={{ .Beacon.Bandwidth }}
+
+# Beacon TX power (dBm).
+power={{ .Beacon.Power }}
+
+# Beacon information descriptor.
+info_desc={{ .Beacon.InfoDesc }}
+
+
+# GPS and location (optional).
+#
+# By default, the GPS and location configuration of the base configuration
+# file is kept. The options below that are set, replace the corresponding
+# gateway_conf settings. When one of the options is set, fake_gps is always
+# set.
+[location]
+# Path to the GPS tty (e.g. /dev/ttyAMA0).
+#
+# When set, LoRa Channel Manager validates that the tty exists.
+gps_tty_path="{{ .Location.GPSTTYPath }}"
+
+# Disable the GPS (removes the gps_tty_path).
+gps_disable={{ .Location.GPSDisable }}
+
+# Use the reference location as fake GPS location.
+fake_gps={{ .Location.FakeGPS }}
+
+# Reference latitude and longitude (decimal degrees, e.g. 52.3676) and
+# altitude (meters) of the gateway.
+#
+# When not set, the ref_latitude, ref_longitude and ref_altitude of the base
+# configuration file are kept.
+{{ if .Location.Latitude }}latitude={{ .Location.LatitudeValue }}{{ else }}# latitude=52.3676{{ end }}
+{{ if .Location.Longitude }}longitude={{ .Location.LongitudeValue }}{{ else }}# longitude=4.9041{{ end }}
+{{ if .Location.Altitude }}altitude={{ .Location.AltitudeValue }}{{ else }}# altitude=5{{ end }}
+
+
+# Listen-before-talk (optional).
+#
+# LBT is required in Japan (AS_923) and Korea (KR_920_923). By default,
+# the lbt_cfg of the base configuration file is kept. When enabled, the
+# LBT channels are generated from the channel-plan, covering all
+# frequencies used for TX (the channels, the RX2 frequency of the band and
+# the beacon frequency). When not enabled but one of the other options is
+# set, LBT is disabled.
+[lbt]
+# Enable LBT.
+enable={{ .LBT.Enable }}
+
+# RSSI (dBm) above which a channel is considered busy.
+#
+# When 0, the default of the band is used (AS_923: -80, KR_920_923: -65).
+rssi_target={{ .LBT.RSSITarget }}
+
+# Scan time (µs, 128 or 5000).
+#
+# When 0, the default of the band is used (5000).
+scan_time={{ .LBT.ScanTime }}
+
+
+# Radio hardware (optional).
+#
+# By default, the radio types (radio_N.type) of the base configuration file
+# are used. The radios are only placed within the frequency range of their
+# type (SX1255: 400 - 510 MHz, SX1257: 862 - 1020 MHz, SX1250: 150 - 960 MHz)
+# and an error is logged when the channel-plan can not be covered by the
+# radios.
+[radio_0]
+# Radio type (SX1255, SX1257 or SX1250).
+#
+# When set, this overrides the type of the base configuration file.
+type="{{ .Radio0.Type }}"
+
+# Allowed frequency range of the radio (Hz).
+#
+# Use this when the RF front-end or SAW filter of the radio limits the
+# frequency range. When 0, the frequency range of the radio type is used.
+freq_min={{ .Radio0.FreqMin }}
+freq_max={{ .Radio0.FreqMax }}
+
+[radio_1]
+# See [radio_0].
+type="{{ .Radio1.Type }}"
+freq_min={{ .Radio1.FreqMin }}
+freq_max={{ .Radio1.FreqMax }}
+
+
+# Packet-forwarder reload.
+#
+# Changes to the radios, channels, TX or LBT configuration require the
+# concentrator to be re-initialized and always restart the packet-forwarder
+# (pf_restart_command). Changes which only affect the gateway_conf settings
+# ([packet_forwarder], [beacon] and [location]) can be applied by reloading
+# the packet-forwarder, when supported. When the reload fails, the
+# packet-forwarder is restarted.
+[reload]
+# Reload strategy.
+#
+# Valid options are:
+#   * restart: restart the packet-forwarder (pf_restart_command)
+#   * sighup:  send SIGHUP to the packet-forwarder process (pid_file), this
+#              requires a packet-forwarder reloading its configuration on
+#              SIGHUP (the stock lora_pkt_fwd exits on SIGHUP)
+#   * socket:  write control_command to the control socket (control_socket)
+strategy="{{ .Reload.Strategy }}"
+
+# File containing the pid of the packet-forwarder (sighup strategy).
+pid_file="{{ .Reload.PIDFile }}"
+
+# Path to the control (unix) socket of the packet-forwarder (socket
+# strategy).
+control_socket="{{ .Reload.ControlSocket }}"
+
+# Command written to the control socket (socket strategy).
+control_command="{{ .Reload.ControlCommand }}"
+
+
+# Maintenance windows (optional).
+#
+# By default, configuration updates are applied immediately. When one or
+# multiple maintenance windows are configured, updates are only applied
+# within a window. Outside the windows, updates are planned (validated) and
+# staged until the next window. Sending SIGUSR1 to LoRa Channel Manager
+# applies the staged updates immediately (e.g. for urgent changes).
+#
+# Note: updates are applied on the next configuration poll within a window,
+# make sure the windows are longer than the config_poll_interval.
+[maintenance]
+# Cron-like maintenance windows.
+#
+# Each window consists of the minute, hour, day of month, month and day of
+# week (0 = Sunday) fields and is open during every minute matching the
+# expression. Each field supports *, values, ranges (a-b), lists (a,b) and
+# steps (*/n). E.g. "* 2-4 * * 1-5" is open from 02:00 until 04:59 on
+# weekdays.
+windows=[{{ range $i, $w := .Maintenance.Windows }}{{ if $i }}, {{ end }}"{{ $w }}"{{ end }}]
+
+# Timezone of the maintenance windows (e.g. Europe/Amsterdam).
+timezone="{{ .Maintenance.Timezone }}"
+
+
+# Dampening (optional).
+#
+# These settings protect the packet-forwarder against configuration updates
+# flapping on the server (e.g. two administrators editing the channel-plan).
+# Suppressed updates are logged and applied once allowed by the settings
+# below. Updates that do not change the written configuration are never
+# suppressed. Settings left 0 are disabled.
+[dampening]
+# Minimum time between two packet-forwarder restarts or reloads.
+min_restart_interval="{{ .Dampening.MinRestartInterval }}"
+
+# Maximum number of packet-forwarder restarts or reloads per hour.
+max_restarts_per_hour={{ .Dampening.MaxRestartsPerHour }}
+
+# Number of consecutive polls the planned configuration must be stable
+# before it is applied.
+settle_polls={{ .Dampening.SettlePolls }}
+
+
+# Configuration update reports (optional).
+#
+# When set, the outcome of each configuration update (the applied UpdatedAt,
+# radio center frequencies and channel to IF mapping, or the validation,
+# write or restart error) is posted as JSON to this url, so that the server
+# can tell whether the gateway applied the configuration.
+[report]
+# URL to post the reports to (e.g. https://ns.example.com/api/gateways/report).
+url="{{ .Report.URL }}"
+
+# Bearer token to authenticate the reports with (optional). The token is
+# only sent over https.
+token="{{ .Report.Token }}"
+
+# Authenticate the reports with the JWT token of the [gateway_server]
+# (only set this when the report url is served by the gateway-server).
+# Can not be combined with token.
+gw_jwt={{ .Report.GWJWT }}
+
+# Timeout of posting a report.
+timeout="{{ .Report.Timeout }}"
+
+
+# Hooks (optional).
+#
+# The hooks are fired on the configuration lifecycle events:
+#
+#   pre-apply           before the configuration is written
+#   post-apply          after the packet-forwarder has been restarted (or
+#                       reloaded)
+#   apply-failed        when the configuration could not be planned, written
+#                       or the packet-forwarder could not be restarted
+#   rollback            after the previously applied configuration has been
+#                       restored because applying the new configuration failed
+#   server-unreachable  when the configuration could not be fetched
+#
+# The event is passed as JSON on stdin to the command (the event name is also
+# set as HOOK_EVENT environment variable) and is posted as JSON to the url.
+# Per gateway, the hooks are fired in order and one at a time. Hook errors are
+# logged, but never block the configuration update.
+[hooks]
+# Command to execute (e.g. /usr/local/bin/notify-config-event).
+command="{{ .Hooks.Command }}"
+
+# URL to post the events to (e.g. https://hooks.example.com/lora).
+url="{{ .Hooks.URL }}"
+
+# Bearer token to authenticate the events posted to the url with (optional).
+# The token is only sent over https. The JWT token of the [gateway_server]
+# is never sent to the url.
+token="{{ .Hooks.Token }}"
+
+# Events to fire (all events when empty).
+events=[{{ range $i, $e := .Hooks.Events }}{{ if $i }}, {{ end }}"{{ $e }}"{{ end }}]
+
+# Timeout of each hook.
+timeout="{{ .Hooks.Timeout }}"
+
+
+# Prometheus metrics.
+[metrics]
+# ip:port to expose the Prometheus metrics on (e.g. 0.0.0.0:8070).
+#
+# The metrics are exposed at /metrics and the last planned configuration
+# of each gateway (including the regional policy annotations and warnings)
+# at /status. Leave blank to disable.
+bind="{{ .Metrics.Bind }}"
+
+
+# Gateways to manage (optional).
+#
+# When one or multiple gateways are defined, these are managed instead of
+# the single gateway defined by gw_mac, base_config_file and
+# output_config_file under [general]. This makes it possible to manage
+# multiple packet-forwarder instances from a single process. Each gateway
+# is updated independently. When pf_restart_command is left blank, the
+# pf_restart_command under [general] is used. The [tx], [packet_forwarder],
+# [beacon], [location], [lbt], [radio_0], [radio_1] and [reload]
+# configuration can be overridden per gateway using a [gateways.tx],
+# [gateways.packet_forwarder], [gateways.beacon], [gateways.location],
+# [gateways.lbt], [gateways.radio_0], [gateways.radio_1] and
+# [gateways.reload] section.
+#
+# With gw_mac_source="config-file", the mac of each gateway is discovered
+# from (and must match) the gateway_ID of its base_config_file.
+#
+# Example:
+# [[gateways]]
+# mac="0102030405060708"
+# base_config_file="/etc/lora-pkt-fwd/board0/global_conf.json"
+# output_config_file="/etc/lora-pkt-fwd/board0/local_conf.json"
+# pf_restart_command="systemctl restart lora-pkt-fwd@board0"
+#
+# [gateways.tx]
+# antenna_gain=6
+# cable_loss=2
+{{ range .Gateways }}
+[[gateways]]
+mac="{{ .MAC }}"
+base_config_file="{{ .BaseConfigFile }}"
+output_config_file="{{ .OutputConfigFile }}"
+pf_restart_command="{{ .PFRestartCommand }}"
+{{ if .TX }}
+[gateways.tx]
+disable={{ .TX.Disable }}
+radio={{ .TX.RadioValue }}
+freq_min={{ .TX.FreqMin }}
+freq_max={{ .TX.FreqMax }}
+max_eirp={{ .TX.MaxEIRP }}
+antenna_gain={{ .TX.AntennaGain }}
+cable_loss={{ .TX.CableLoss }}
+{{ end }}{{ with .PacketForwarder }}
+[gateways.packet_forwarder]
+server_address="{{ .ServerAddress }}"
+serv_port_up={{ .ServPortUp }}
+serv_port_down={{ .ServPortDown }}
+keepalive_interval={{ .KeepaliveInterval }}
+stat_interval={{ .StatInterval }}
+push_timeout_ms={{ .PushTimeoutMS }}
+{{ with .ForwardCRCValid }}forward_crc_valid={{ . }}
+{{ end }}{{ with .ForwardCRCError }}forward_crc_error={{ . }}
+{{ end }}{{ with .ForwardCRCDisabled }}forward_crc_disabled={{ . }}
+{{ end }}{{ end }}{{ with .Beacon }}
+[gateways.beacon]
+enable={{ .Enable }}
+period={{ .Period }}
+freq={{ .Freq }}
+freq_nb={{ .FreqNb }}
+freq_step={{ .FreqStep }}
+spread_factor={{ .SpreadFactor }}
+bandwidth={{ .Bandwidth }}
+power={{ .Power }}
+info_desc={{ .InfoDesc }}
+{{ end }}{{ with .Location }}
+[gateways.location]
+gps_tty_path="{{ .GPSTTYPath }}"
+gps_disable={{ .GPSDisable }}
+fake_gps={{ .FakeGPS }}
+{{ if .Latitude }}latitude={{ .LatitudeValue }}
+{{ end }}{{ if .Longitude }}longitude={{ .LongitudeValue }}
+{{ end }}{{ if .Altitude }}altitude={{ .AltitudeValue }}
+{{ end }}
+{{ end }}{{ with .LBT }}
+[gateways.lbt]
+enable={{ .Enable }}
+rssi_target={{ .RSSITarget }}
+scan_time={{ .ScanTime }}
+{{ end }}{{ with .Radio0 }}
+[gateways.radio_0]
+type="{{ .Type }}"
+freq_min={{ .FreqMin }}
+freq_max={{ .FreqMax }}
+{{ end }}{{ with .Radio1 }}
+[gateways.radio_1]
+type="{{ .Type }}"
+freq_min={{ .FreqMin }}
+freq_max={{ .FreqMax }}
+{{ end }}{{ with .Reload }}
+[gateways.reload]
+strategy="{{ .Strategy }}"
+pid_file="{{ .PIDFile }}"
+control_socket="{{ .ControlSocket }}"
+control_command="{{ .ControlCommand }}"
+{{ end }}{{ end }}`
+
+// printConfigFile prints a commented configuration file template, populated
+// with the current configuration values.
+func printConfigFile(c *cli.Context) error {
+	t := template.Must(template.New("config").Parse(configTemplate))
+	if err := t.Execute(os.Stdout, fileConfigFromContext(c)); err != nil {
+		return errors.Wrap(err, "execute config template error")
+	}
+	return nil
+}
@@ -1,17 +1,22 @@
 package main
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"io/ioutil"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
-	"github.com/brocaar/lora-channel-manager/internal/config"
-	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lora-channel-manager/internal/gwclient"
+	"github.com/brocaar/lora-channel-manager/manager"
+	"github.com/brocaar/lorawan"
+	"github.com/brocaar/lorawan/band"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/urfave/cli"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -20,103 +25,668 @@ import (
 
 var version string // set by the compiler
 
-type jwt struct {
-	token string
-}
-
-func (j jwt) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
-	return map[string]string{
-		"authorization": j.token,
-	}, nil
-}
-
-func (j jwt) RequireTransportSecurity() bool {
-	return false
+// gateway defines a gateway (packet-forwarder instance) to manage.
+type gateway struct {
+	MAC              lorawan.EUI64
+	BaseConfigFile   string
+	OutputConfigFile string
+	PFRestartCommand string
+	Reloader         manager.Reloader
+	TX               *manager.TXConfig
+	Forwarder        *manager.ForwarderConfig
+	Beacon           *manager.BeaconConfig
+	Location         *manager.LocationConfig
+	LBT              *manager.LBTConfig
+	Radios           [2]manager.RadioHardwareConfig
 }
 
 func run(c *cli.Context) error {
-	// set config variables
-	if err := config.GatewayMAC.UnmarshalText([]byte(c.String("gw-mac"))); err != nil {
-		log.Fatalf("invalid gw-mac: %s", err)
+	// get the gateways to manage
+	gateways, err := getGateways(c)
+	if err != nil {
+		log.Fatalf("get gateways error: %s", err)
 	}
-	config.BaseConfigFile = c.String("base-config-file")
-	config.OutputConfigFile = c.String("output-config-file")
-	config.PFRestartCommand = c.String("pf-restart-command")
-	config.ConfigPollInterval = c.Duration("config-poll-interval")
 
 	log.WithFields(log.Fields{
-		"version":            version,
-		"docs":               "https://docs.loraserver.io/",
-		"base_config_file":   config.BaseConfigFile,
-		"output_config_file": config.OutputConfigFile,
+		"version":  version,
+		"docs":     "https://docs.loraserver.io/",
+		"gateways": len(gateways),
 	}).Info("starting LoRa Channel Manager")
 
-	// connect to gateway api server
+	jwtCreds, err := getJWTCredentials(c)
+	if err != nil {
+		log.Fatalf("load jwt token error: %s", err)
+	}
+
+	gwClient, err := newGatewayClient(c, jwtCreds)
+	if err != nil {
+		log.Fatalf("gateway-server client error: %s", err)
+	}
+
+	// run a manager per gateway
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var managers []*manager.Manager
+	for _, g := range gateways {
+		log.WithFields(log.Fields{
+			"gw_mac":             g.MAC,
+			"base_config_file":   g.BaseConfigFile,
+			"output_config_file": g.OutputConfigFile,
+			"rollout_bucket":     manager.RolloutBucket(g.MAC),
+		}).Info("starting channel-configuration manager for gateway")
+
+		m, err := newManager(c, g, gwClient, jwtCreds)
+		if err != nil {
+			log.Fatalf("new manager error: %s", err)
+		}
+		managers = append(managers, m)
+		go m.Run(ctx)
+	}
+
+	// start the metrics and status endpoint
+	if c.String("metrics-bind") != "" {
+		go startMetricsServer(c.String("metrics-bind"), managers)
+	}
+
+	// wait for stop signal, SIGUSR1 applies the staged configuration
+	// updates immediately
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1)
+	for sig := range sigChan {
+		log.WithField("signal", sig).Info("signal received")
+		if sig != syscall.SIGUSR1 {
+			break
+		}
+		for _, m := range managers {
+			m.ApplyNow()
+		}
+	}
+
+	return nil
+}
+
+// plan prints the planned configuration of each gateway, without writing
+// it or restarting the packet-forwarder.
+func plan(c *cli.Context) error {
+	// the flags are defined at the app (global) level
+	c = c.Parent()
+
+	gateways, err := getGateways(c)
+	if err != nil {
+		log.Fatalf("get gateways error: %s", err)
+	}
+
+	jwtCreds, err := getJWTCredentials(c)
+	if err != nil {
+		log.Fatalf("load jwt token error: %s", err)
+	}
+
+	gwClient, err := newGatewayClient(c, jwtCreds)
+	if err != nil {
+		log.Fatalf("gateway-server client error: %s", err)
+	}
+
+	var out []gatewayStatus
+	for _, g := range gateways {
+		m, err := newManager(c, g, gwClient, jwtCreds)
+		if err != nil {
+			log.Fatalf("new manager error: %s", err)
+		}
+
+		conf, err := m.Plan(context.Background())
+		if err != nil {
+			log.Fatalf("plan gateway %s error: %s", g.MAC, err)
+		}
+		out = append(out, gatewayStatus{MAC: g.MAC, Plan: &conf})
+	}
+
+	b, err := json.MarshalIndent(out, "", "    ")
+	if err != nil {
+		log.Fatalf("marshal plan error: %s", err)
+	}
+	fmt.Println(string(b))
+
+	return nil
+}
+
+// gatewayStatus contains the planned configuration of a gateway.
+type gatewayStatus struct {
+	MAC    lorawan.EUI64
+	Plan   *manager.GatewayConfiguration
+	Staged *manager.GatewayConfiguration `json:",omitempty"`
+}
+
+// getJWTCredentials returns the JWT credentials of the gateway-server client
+// from the cli flags, or nil when no JWT token is configured.
+func getJWTCredentials(c *cli.Context) (*gwclient.JWTCredentials, error) {
+	if c.String("gw-client-jwt-token") == "" && c.String("gw-client-jwt-token-file") == "" {
+		return nil, nil
+	}
+	return gwclient.NewJWTCredentials(c.String("gw-client-jwt-token"), c.String("gw-client-jwt-token-file"), c.Bool("gw-client-jwt-allow-insecure"))
+}
+
+// newGatewayClient connects to the gateway api server(s), using the given
+// JWT credentials (optional).
+func newGatewayClient(c *cli.Context, jwtCreds *gwclient.JWTCredentials) (*gwclient.FailoverClient, error) {
+	gwServers := gwclient.ParseEndpoints(c.String("gw-server"))
+	if c.String("gw-server-srv") != "" {
+		var err error
+		gwServers, err = gwclient.LookupSRVEndpoints(c.String("gw-server-srv"))
+		if err != nil {
+			return nil, errors.Wrap(err, "lookup gateway-server srv record error")
+		}
+	}
 	log.WithFields(log.Fields{
-		"server":   c.String("gw-server"),
-		"ca-cert":  c.String("gw-client-ca-cert"),
-		"tls-cert": c.String("gw-client-tls-cert"),
-		"tls-key":  c.String("gw-client-tls-key"),
+		"servers":        gwServers,
+		"balancing":      c.String("gw-server-balancing"),
+		"ca-cert":        c.String("gw-client-ca-cert"),
+		"tls-cert":       c.String("gw-client-tls-cert"),
+		"tls-key":        c.String("gw-client-tls-key"),
+		"jwt-token-file": c.String("gw-client-jwt-token-file"),
 	}).Info("connecting to gateway-server")
-	gwDialOptions := []grpc.DialOption{
-		grpc.WithPerRPCCredentials(jwt{token: c.String("gw-client-jwt-token")}),
+	tlsEnabled := c.String("gw-client-tls-cert") != "" || c.String("gw-client-tls-key") != "" || c.String("gw-client-ca-cert") != "" || c.Bool("gw-client-ca-cert-system-roots")
+
+	var gwDialOptions []grpc.DialOption
+	if jwtCreds != nil {
+		if !tlsEnabled && !c.Bool("gw-client-jwt-allow-insecure") {
+			return nil, errors.New("the jwt token is only sent over a secure (tls) connection, configure tls or set gw-client-jwt-allow-insecure to send it over an insecure connection")
+		}
+		gwDialOptions = append(gwDialOptions, grpc.WithPerRPCCredentials(jwtCreds))
 	}
-	if c.String("gw-client-tls-cert") != "" && c.String("gw-client-tls-key") != "" {
-		gwDialOptions = append(gwDialOptions, grpc.WithTransportCredentials(
-			mustGetTransportCredentials(c.String("gw-client-tls-cert"), c.String("gw-client-tls-key"), c.String("gw-client-ca-cert"), false),
-		))
+	if tlsEnabled {
+		tlsConfig, err := gwclient.NewTLSConfig(gwclient.TLSOptions{
+			CACert:      c.String("gw-client-ca-cert"),
+			SystemRoots: c.Bool("gw-client-ca-cert-system-roots"),
+			TLSCert:     c.String("gw-client-tls-cert"),
+			TLSKey:      c.String("gw-client-tls-key"),
+			ServerName:  c.String("gw-client-tls-server-name"),
+			MinVersion:  c.String("gw-client-tls-min-version"),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "gateway-server client tls config error")
+		}
+		gwDialOptions = append(gwDialOptions, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
 	} else {
 		gwDialOptions = append(gwDialOptions, grpc.WithInsecure())
 	}
-	gwConn, err := grpc.Dial(c.String("gw-server"), gwDialOptions...)
+	gwClient, err := gwclient.NewFailoverClient(gwServers, c.String("gw-server-balancing"), c.Duration("gw-server-retry-interval"), gwDialOptions...)
 	if err != nil {
-		log.Fatalf("gateway-server dial error: %s", err)
+		return nil, errors.Wrap(err, "gateway-server dial error")
 	}
-	config.GatewayClient = gw.NewGatewayClient(gwConn)
+	return gwClient, nil
+}
 
-	// run update config loop
-	go config.UpdateConfigLoop()
+// newManager returns a new manager for the given gateway. The JWT
+// credentials of the gateway-server client (optional) are only used by the
+// reporter when explicitly enabled and never by the hooks.
+func newManager(c *cli.Context, g gateway, gwClient *gwclient.FailoverClient, jwtCreds *gwclient.JWTCredentials) (*manager.Manager, error) {
+	dwellTime := lorawan.DwellTimeNoLimit
+	if c.Bool("dwell-time-400ms") {
+		dwellTime = lorawan.DwellTime400ms
+	}
 
-	// wait for stop signal
-	sigChan := make(chan os.Signal)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	log.WithField("signal", <-sigChan).Info("signal received")
+	windows, err := getMaintenanceWindows(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "get maintenance windows error")
+	}
 
-	return nil
+	opts := []manager.Option{
+		manager.WithConfigSource(manager.GatewayClientSource{Client: gwClient}),
+		manager.WithPlanner(manager.DefaultPlanner{
+			TX:                 g.TX,
+			Band:               band.Name(c.String("band")),
+			DwellTime:          dwellTime,
+			Forwarder:          g.Forwarder,
+			Beacon:             g.Beacon,
+			Location:           g.Location,
+			LBT:                g.LBT,
+			Radios:             g.Radios,
+			BaseConfigFile:     g.BaseConfigFile,
+			PreviousConfigFile: g.OutputConfigFile,
+		}),
+		manager.WithWriter(manager.FileWriter{
+			BaseConfigFile:   g.BaseConfigFile,
+			OutputConfigFile: g.OutputConfigFile,
+		}),
+		manager.WithRestarter(manager.CommandRestarter{Command: g.PFRestartCommand}),
+		manager.WithPollInterval(c.Duration("config-poll-interval")),
+	}
+	if g.Reloader != nil {
+		opts = append(opts, manager.WithReloader(g.Reloader))
+	}
+	if len(windows) != 0 {
+		opts = append(opts, manager.WithMaintenanceWindows(windows...))
+	}
+	reporter, err := getReporter(c, jwtCreds)
+	if err != nil {
+		return nil, errors.Wrap(err, "get reporter error")
+	}
+	if reporter != nil {
+		opts = append(opts, manager.WithReporter(reporter))
+	}
+	hooks, err := getHooks(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "get hooks error")
+	}
+	if len(hooks) != 0 {
+		opts = append(opts, manager.WithHooks(hooks...))
+	}
+	opts = append(opts, manager.WithDampening(manager.DampeningConfig{
+		MinRestartInterval: c.Duration("dampening-min-restart-interval"),
+		MaxRestartsPerHour: c.Int("dampening-max-restarts-per-hour"),
+		SettlePolls:        c.Int("dampening-settle-polls"),
+	}))
+
+	return manager.New(g.MAC, opts...)
+}
+
+func startMetricsServer(bind string, managers []*manager.Manager) {
+	log.WithField("bind", bind).Info("starting prometheus metrics and status endpoint")
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		var out []gatewayStatus
+		for _, m := range managers {
+			out = append(out, gatewayStatus{MAC: m.MAC(), Plan: m.LastPlan(), Staged: m.Staged()})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			log.WithError(err).Error("encode status error")
+		}
+	})
+	log.Fatal(http.ListenAndServe(bind, mux))
 }
 
-func mustGetTransportCredentials(tlsCert, tlsKey, caCert string, verifyClientCert bool) credentials.TransportCredentials {
-	var caCertPool *x509.CertPool
-	cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+// getGateways returns the gateways to manage. When the configuration file
+// contains a list of gateways, these are returned. Else a single gateway
+// is returned, based on the cli flags.
+func getGateways(c *cli.Context) ([]gateway, error) {
+	forwarderConfig, err := getForwarderConfig(c)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"cert": tlsCert,
-			"key":  tlsKey,
-		}).Fatalf("load key-pair error: %s", err)
+		return nil, errors.Wrap(err, "get packet-forwarder config error")
 	}
 
-	if caCert != "" {
-		rawCaCert, err := ioutil.ReadFile(caCert)
+	reloader, err := getReloadConfig(c).reloader()
+	if err != nil {
+		return nil, errors.Wrap(err, "get packet-forwarder reloader error")
+	}
+
+	location, err := getLocationConfig(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "get location config error")
+	}
+
+	if len(fileConf.Gateways) == 0 {
+		mac, err := getGatewayMAC(c)
 		if err != nil {
-			log.WithField("ca", caCert).Fatalf("load ca cert error: %s", err)
+			return nil, errors.Wrap(err, "get gateway mac error")
+		}
+
+		return []gateway{
+			{
+				MAC:              mac,
+				BaseConfigFile:   c.String("base-config-file"),
+				OutputConfigFile: c.String("output-config-file"),
+				PFRestartCommand: c.String("pf-restart-command"),
+				Reloader:         reloader,
+				TX:               getTXConfig(c),
+				Forwarder:        forwarderConfig,
+				Beacon:           getBeaconConfig(c),
+				Location:         location,
+				LBT:              getLBTConfig(c),
+				Radios:           getRadioConfigs(c),
+			},
+		}, nil
+	}
+
+	var out []gateway
+	seen := make(map[lorawan.EUI64]bool)
+	for i, g := range fileConf.Gateways {
+		gw := gateway{
+			BaseConfigFile:   g.BaseConfigFile,
+			OutputConfigFile: g.OutputConfigFile,
+			PFRestartCommand: g.PFRestartCommand,
+			Reloader:         reloader,
+			TX:               getTXConfig(c),
+			Forwarder:        forwarderConfig,
+			Beacon:           getBeaconConfig(c),
+			Location:         location,
+			LBT:              getLBTConfig(c),
+			Radios:           getRadioConfigs(c),
+		}
+		if g.TX != nil {
+			gw.TX = g.TX.txConfig()
 		}
+		if g.PacketForwarder != nil {
+			gw.Forwarder = g.PacketForwarder.forwarderConfig()
+		}
+		if g.Beacon != nil {
+			gw.Beacon = g.Beacon.beaconConfig()
+		}
+		if g.Location != nil {
+			gw.Location = g.Location.locationConfig()
+		}
+		if g.LBT != nil {
+			gw.LBT = g.LBT.lbtConfig()
+		}
+		if g.Radio0 != nil {
+			gw.Radios[0] = g.Radio0.radioConfig()
+		}
+		if g.Radio1 != nil {
+			gw.Radios[1] = g.Radio1.radioConfig()
+		}
+		if g.Reload != nil {
+			if gw.Reloader, err = g.Reload.reloader(); err != nil {
+				return nil, errors.Wrapf(err, "invalid reload configuration for gateway %d", i)
+			}
+		}
+		if gw.MAC, err = getGatewayEntryMAC(c, g); err != nil {
+			return nil, errors.Wrapf(err, "get mac for gateway %d error", i)
+		}
+		if seen[gw.MAC] {
+			return nil, fmt.Errorf("gateway %s is defined more than once", gw.MAC)
+		}
+		seen[gw.MAC] = true
 
-		caCertPool = x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(rawCaCert)
+		// fallback on the global restart command
+		if gw.PFRestartCommand == "" {
+			gw.PFRestartCommand = c.String("pf-restart-command")
+		}
+
+		out = append(out, gw)
 	}
 
-	if verifyClientCert {
-		return credentials.NewTLS(&tls.Config{
-			Certificates: []tls.Certificate{cert},
-			RootCAs:      caCertPool,
-			ClientAuth:   tls.RequireAndVerifyClientCert,
+	return out, nil
+}
+
+// getTXConfig returns the TX configuration from the cli flags.
+func getTXConfig(c *cli.Context) *manager.TXConfig {
+	return fileConfigTX{
+		Disable:     c.Bool("tx-disable"),
+		Radio:       txRadioFlag(c.Int("tx-radio")),
+		FreqMin:     c.Int("tx-freq-min"),
+		FreqMax:     c.Int("tx-freq-max"),
+		MaxEIRP:     c.Int("tx-max-eirp"),
+		AntennaGain: c.Int("tx-antenna-gain"),
+		CableLoss:   c.Int("tx-cable-loss"),
+	}.txConfig()
+}
+
+// getBeaconConfig returns the Class-B beacon configuration from the cli
+// flags.
+func getBeaconConfig(c *cli.Context) *manager.BeaconConfig {
+	return fileConfigBeacon{
+		Enable:       c.Bool("beacon-enable"),
+		Period:       c.Int("beacon-period"),
+		Freq:         c.Int("beacon-freq"),
+		FreqNb:       c.Int("beacon-freq-nb"),
+		FreqStep:     c.Int("beacon-freq-step"),
+		SpreadFactor: c.Int("beacon-spread-factor"),
+		Bandwidth:    c.Int("beacon-bandwidth"),
+		Power:        c.Int("beacon-power"),
+		InfoDesc:     c.Int("beacon-info-desc"),
+	}.beaconConfig()
+}
+
+// getLocationConfig returns the GPS and location configuration from the
+// cli flags.
+func getLocationConfig(c *cli.Context) (*manager.LocationConfig, error) {
+	f, err := locationFromFlags(
+		c.String("location-gps-tty-path"),
+		c.Bool("location-gps-disable"),
+		c.Bool("location-fake-gps"),
+		c.String("location-latitude"),
+		c.String("location-longitude"),
+		c.String("location-altitude"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return f.locationConfig(), nil
+}
+
+// getLBTConfig returns the listen-before-talk configuration from the cli
+// flags.
+func getLBTConfig(c *cli.Context) *manager.LBTConfig {
+	return fileConfigLBT{
+		Enable:     c.Bool("lbt-enable"),
+		RSSITarget: c.Int("lbt-rssi-target"),
+		ScanTime:   c.Int("lbt-scan-time"),
+	}.lbtConfig()
+}
+
+// getRadioConfigs returns the radio hardware configuration from the cli
+// flags.
+func getRadioConfigs(c *cli.Context) [2]manager.RadioHardwareConfig {
+	var out [2]manager.RadioHardwareConfig
+	for i := range out {
+		out[i] = fileConfigRadio{
+			Type:    c.String(fmt.Sprintf("radio-%d-type", i)),
+			FreqMin: c.Int(fmt.Sprintf("radio-%d-freq-min", i)),
+			FreqMax: c.Int(fmt.Sprintf("radio-%d-freq-max", i)),
+		}.radioConfig()
+	}
+	return out
+}
+
+// getReloadConfig returns the packet-forwarder reload configuration from the
+// cli flags.
+func getReloadConfig(c *cli.Context) fileConfigReload {
+	return fileConfigReload{
+		Strategy:       c.String("pf-reload-strategy"),
+		PIDFile:        c.String("pf-reload-pid-file"),
+		ControlSocket:  c.String("pf-reload-control-socket"),
+		ControlCommand: c.String("pf-reload-control-command"),
+	}
+}
+
+// getReporter returns the reporter from the cli flags, or nil when no
+// report url is configured. The reporter authenticates using the report
+// token or, when explicitly enabled, the JWT credentials of the
+// gateway-server client.
+func getReporter(c *cli.Context, jwtCreds *gwclient.JWTCredentials) (manager.Reporter, error) {
+	if c.String("report-url") == "" {
+		return nil, nil
+	}
+
+	r := manager.HTTPReporter{
+		URL:    c.String("report-url"),
+		Client: &http.Client{Timeout: c.Duration("report-timeout")},
+	}
+	switch {
+	case c.String("report-token") != "" && c.Bool("report-gw-jwt"):
+		return nil, errors.New("report-token and report-gw-jwt are mutually exclusive")
+	case c.String("report-token") != "":
+		r.Credentials = manager.TokenCredentials(c.String("report-token"))
+	case c.Bool("report-gw-jwt"):
+		if jwtCreds == nil {
+			return nil, errors.New("report-gw-jwt requires the gateway-server client jwt token to be set")
+		}
+		r.Credentials = jwtCreds
+	}
+	return r, nil
+}
+
+// getHooks returns the hooks from the cli flags. The command hook is fired
+// before the webhook. The webhook is authenticated using the hook token
+// (optional), the JWT token of the gateway-server client is never sent to
+// the webhook.
+func getHooks(c *cli.Context) ([]manager.HookConfig, error) {
+	var events []manager.Event
+	for _, e := range strings.Split(c.String("hook-events"), ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		switch ev := manager.Event(e); ev {
+		case manager.EventPreApply, manager.EventPostApply, manager.EventApplyFailed, manager.EventRollback, manager.EventServerUnreachable:
+			events = append(events, ev)
+		default:
+			return nil, fmt.Errorf("invalid hook event: %s", e)
+		}
+	}
+
+	var hooks []manager.HookConfig
+	if c.String("hook-command") != "" {
+		hooks = append(hooks, manager.HookConfig{
+			Hook:    manager.CommandHook{Command: c.String("hook-command")},
+			Events:  events,
+			Timeout: c.Duration("hook-timeout"),
+		})
+	}
+	if c.String("hook-url") != "" {
+		h := manager.WebhookHook{URL: c.String("hook-url")}
+		if c.String("hook-token") != "" {
+			h.Credentials = manager.TokenCredentials(c.String("hook-token"))
+		}
+		hooks = append(hooks, manager.HookConfig{
+			Hook:    h,
+			Events:  events,
+			Timeout: c.Duration("hook-timeout"),
 		})
 	}
+	return hooks, nil
+}
 
-	return credentials.NewTLS(&tls.Config{
-		Certificates: []tls.Certificate{cert},
-		RootCAs:      caCertPool,
-	})
+// getMaintenanceWindows returns the maintenance windows from the cli flags.
+func getMaintenanceWindows(c *cli.Context) ([]manager.MaintenanceWindow, error) {
+	// time.LoadLocation returns UTC for an empty name, a blank timezone
+	// (e.g. an empty MAINTENANCE_TIMEZONE) must use the local timezone
+	name := c.String("maintenance-timezone")
+	if name == "" {
+		name = "Local"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid maintenance-timezone")
+	}
+
+	var out []manager.MaintenanceWindow
+	for _, expr := range strings.Split(c.String("maintenance-windows"), ";") {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		w, err := manager.ParseMaintenanceWindow(expr, loc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+// getForwarderConfig returns the packet-forwarder configuration from the
+// cli flags.
+func getForwarderConfig(c *cli.Context) (*manager.ForwarderConfig, error) {
+	f := fileConfigForwarder{
+		ServerAddress:     c.String("pf-server-address"),
+		ServPortUp:        c.Int("pf-serv-port-up"),
+		ServPortDown:      c.Int("pf-serv-port-down"),
+		KeepaliveInterval: c.Int("pf-keepalive-interval"),
+		StatInterval:      c.Int("pf-stat-interval"),
+		PushTimeoutMS:     c.Int("pf-push-timeout-ms"),
+	}
+
+	var err error
+	for name, b := range map[string]**bool{
+		"pf-forward-crc-valid":    &f.ForwardCRCValid,
+		"pf-forward-crc-error":    &f.ForwardCRCError,
+		"pf-forward-crc-disabled": &f.ForwardCRCDisabled,
+	} {
+		if *b, err = parseOptionalBool(c.String(name)); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", name)
+		}
+	}
+
+	return f.forwarderConfig(), nil
+}
+
+// getGatewayMAC returns the gateway MAC from --gw-mac and / or the
+// configured discovery source. When both are set, they must match.
+func getGatewayMAC(c *cli.Context) (lorawan.EUI64, error) {
+	var configured lorawan.EUI64
+	if c.String("gw-mac") != "" {
+		if err := configured.UnmarshalText([]byte(c.String("gw-mac"))); err != nil {
+			return configured, errors.Wrap(err, "invalid gw-mac")
+		}
+	}
+
+	var arg string
+	switch c.String("gw-mac-source") {
+	case "":
+		if c.String("gw-mac") == "" {
+			return configured, errors.New("gw-mac or gw-mac-source must be set")
+		}
+		return configured, nil
+	case manager.GatewayMACSourceConfigFile:
+		arg = c.String("gw-mac-config-file")
+		if arg == "" {
+			arg = c.String("base-config-file")
+		}
+	case manager.GatewayMACSourceInterface:
+		arg = c.String("gw-mac-interface")
+	case manager.GatewayMACSourceCommand:
+		arg = c.String("gw-mac-command")
+	}
+
+	mac, err := manager.DiscoverGatewayMAC(c.String("gw-mac-source"), arg)
+	if err != nil {
+		return mac, errors.Wrap(err, "discover gateway mac error")
+	}
+	log.WithFields(log.Fields{
+		"source": c.String("gw-mac-source"),
+		"mac":    mac,
+	}).Info("gateway mac discovered")
+
+	if c.String("gw-mac") != "" {
+		if err := manager.CheckGatewayMAC(configured, mac); err != nil {
+			return mac, err
+		}
+	}
+
+	return mac, nil
+}
+
+// getGatewayEntryMAC returns the MAC of the given [[gateways]] entry. With
+// the config-file source, the MAC is discovered from the base configuration
+// file of the entry and must match the configured MAC (when set). The
+// interface and command sources identify a single gateway and can therefore
+// not be used for multiple gateways.
+func getGatewayEntryMAC(c *cli.Context, g fileConfigGateway) (lorawan.EUI64, error) {
+	var configured lorawan.EUI64
+	if g.MAC != "" {
+		if err := configured.UnmarshalText([]byte(g.MAC)); err != nil {
+			return configured, errors.Wrap(err, "invalid mac")
+		}
+	}
+
+	switch c.String("gw-mac-source") {
+	case "":
+		if g.MAC == "" {
+			return configured, errors.New("mac or gw-mac-source must be set")
+		}
+		return configured, nil
+	case manager.GatewayMACSourceConfigFile:
+	default:
+		return configured, fmt.Errorf("gw-mac-source %s can not be used for multiple gateways (use config-file)", c.String("gw-mac-source"))
+	}
+
+	mac, err := manager.GatewayMACFromConfigFile(g.BaseConfigFile)
+	if err != nil {
+		return mac, errors.Wrap(err, "discover gateway mac error")
+	}
+	log.WithFields(log.Fields{
+		"source": c.String("gw-mac-source"),
+		"mac":    mac,
+	}).Info("gateway mac discovered")
+
+	if g.MAC != "" {
+		if err := manager.CheckGatewayMAC(configured, mac); err != nil {
+			return mac, err
+		}
+	}
+
+	return mac, nil
 }
 
 func main() {
@@ -125,19 +695,74 @@ func main() {
 	app.Usage = "channel-configuration daemon for LoRa gateways"
 	app.Version = version
 	app.Copyright = "see http://github.com/brocaar/lora-channel-manager for copyright information"
+	app.Before = loadConfigFile
 	app.Action = run
+	app.Commands = []cli.Command{
+		{
+			Name:   "configfile",
+			Usage:  "print the configuration file template (populated with the current configuration values)",
+			Action: printConfigFile,
+		},
+		{
+			Name:   "plan",
+			Usage:  "print the planned configuration of each gateway (including the regional policy annotations and warnings), without applying it",
+			Action: plan,
+		},
+	}
 	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "config",
+			Usage:  "path to the TOML configuration file (optional, flags and environment variables take precedence)",
+			EnvVar: "CONFIG_FILE",
+		},
 		cli.StringFlag{
 			Name:   "gw-mac",
 			Usage:  "mac address of the gateway",
 			EnvVar: "GW_MAC",
 		},
+		cli.StringFlag{
+			Name:   "gw-mac-source",
+			Usage:  "discover the gateway mac from the config-file, interface or command source (optional, must match gw-mac when both are set)",
+			EnvVar: "GW_MAC_SOURCE",
+		},
+		cli.StringFlag{
+			Name:   "gw-mac-config-file",
+			Usage:  "packet-forwarder configuration file containing the gateway_ID for the config-file source (default: base-config-file)",
+			EnvVar: "GW_MAC_CONFIG_FILE",
+		},
+		cli.StringFlag{
+			Name:   "gw-mac-interface",
+			Usage:  "network interface to derive the gateway mac from for the interface source (e.g. eth0)",
+			EnvVar: "GW_MAC_INTERFACE",
+		},
+		cli.StringFlag{
+			Name:   "gw-mac-command",
+			Usage:  "command printing the gateway mac for the command source",
+			EnvVar: "GW_MAC_COMMAND",
+		},
 		cli.StringFlag{
 			Name:   "gw-server",
-			Usage:  "hostname:ip of the gateway api server",
+			Usage:  "hostname:ip of the gateway api server (use a comma separated list for multiple servers)",
 			Value:  "127.0.0.1:8002",
 			EnvVar: "GW_SERVER",
 		},
+		cli.StringFlag{
+			Name:   "gw-server-srv",
+			Usage:  "dns srv record to lookup the gateway api servers (optional, e.g. _loraserver-gw._tcp.example.com, takes precedence over gw-server)",
+			EnvVar: "GW_SERVER_SRV",
+		},
+		cli.StringFlag{
+			Name:   "gw-server-balancing",
+			Usage:  "balancing over multiple gateway api servers (round-robin or failover)",
+			Value:  gwclient.RoundRobin,
+			EnvVar: "GW_SERVER_BALANCING",
+		},
+		cli.DurationFlag{
+			Name:   "gw-server-retry-interval",
+			Usage:  "interval after which a failing gateway api server is retried",
+			Value:  time.Minute,
+			EnvVar: "GW_SERVER_RETRY_INTERVAL",
+		},
 		cli.StringFlag{
 			Name:   "gw-client-ca-cert",
 			Usage:  "ca certificate used by the gateway-server client (optional)",
@@ -145,19 +770,45 @@ func main() {
 		},
 		cli.StringFlag{
 			Name:   "gw-client-tls-cert",
-			Usage:  "tls certificate used by the gateway-server client (optional)",
+			Usage:  "tls certificate used by the gateway-server client, reloaded on change (optional)",
 			EnvVar: "GW_CLIENT_TLS_CERT",
 		},
 		cli.StringFlag{
 			Name:   "gw-client-tls-key",
-			Usage:  "tls key used by the gateway-server client (optional)",
+			Usage:  "tls key used by the gateway-server client, reloaded on change (optional)",
 			EnvVar: "GW_CLIENT_TLS_KEY",
 		},
+		cli.BoolFlag{
+			Name:   "gw-client-ca-cert-system-roots",
+			Usage:  "use the system root CAs in addition to the ca certificate",
+			EnvVar: "GW_CLIENT_CA_CERT_SYSTEM_ROOTS",
+		},
+		cli.StringFlag{
+			Name:   "gw-client-tls-server-name",
+			Usage:  "server name used to verify the gateway-server certificate (optional, e.g. when connecting by ip)",
+			EnvVar: "GW_CLIENT_TLS_SERVER_NAME",
+		},
+		cli.StringFlag{
+			Name:   "gw-client-tls-min-version",
+			Usage:  "minimum tls version used by the gateway-server client (1.0, 1.1 or 1.2)",
+			Value:  "1.2",
+			EnvVar: "GW_CLIENT_TLS_MIN_VERSION",
+		},
 		cli.StringFlag{
 			Name:   "gw-client-jwt-token",
 			Usage:  "jwt token used by the gateway-server client for authentication (issued by LoRa Server)",
 			EnvVar: "GW_CLIENT_JWT_TOKEN",
 		},
+		cli.StringFlag{
+			Name:   "gw-client-jwt-token-file",
+			Usage:  "file containing the jwt token, re-read on change (optional, takes precedence over gw-client-jwt-token)",
+			EnvVar: "GW_CLIENT_JWT_TOKEN_FILE",
+		},
+		cli.BoolFlag{
+			Name:   "gw-client-jwt-allow-insecure",
+			Usage:  "allow sending the jwt token over an insecure (non-TLS) connection",
+			EnvVar: "GW_CLIENT_JWT_ALLOW_INSECURE",
+		},
 		cli.StringFlag{
 			Name:   "base-config-file",
 			Usage:  "path to the base configuration file",
@@ -173,12 +824,323 @@ func main() {
 			Usage:  "command which must be executed on configuration changes to restart the packet-forwarder",
 			EnvVar: "PF_RESTART_COMMAND",
 		},
+		cli.StringFlag{
+			Name:   "pf-reload-strategy",
+			Usage:  "strategy to apply gateway_conf-only changes (restart, sighup or socket), radio and channel changes always restart the packet-forwarder (sighup requires a packet-forwarder reloading on SIGHUP, the stock lora_pkt_fwd exits)",
+			Value:  "restart",
+			EnvVar: "PF_RELOAD_STRATEGY",
+		},
+		cli.StringFlag{
+			Name:   "pf-reload-pid-file",
+			Usage:  "file containing the pid of the packet-forwarder (sighup strategy)",
+			EnvVar: "PF_RELOAD_PID_FILE",
+		},
+		cli.StringFlag{
+			Name:   "pf-reload-control-socket",
+			Usage:  "path to the control (unix) socket of the packet-forwarder (socket strategy)",
+			EnvVar: "PF_RELOAD_CONTROL_SOCKET",
+		},
+		cli.StringFlag{
+			Name:   "pf-reload-control-command",
+			Usage:  "command written to the control socket (socket strategy)",
+			Value:  "reload",
+			EnvVar: "PF_RELOAD_CONTROL_COMMAND",
+		},
 		cli.DurationFlag{
 			Name:   "config-poll-interval",
 			Usage:  "interval between polling new configuration",
 			Value:  time.Minute * 5,
 			EnvVar: "CONFIG_POLL_INTERVAL",
 		},
+		cli.StringFlag{
+			Name:   "maintenance-windows",
+			Usage:  "cron-like maintenance windows during which configuration updates are applied, separated by ; (e.g. \"* 2-4 * * *\", updates are applied immediately when blank)",
+			EnvVar: "MAINTENANCE_WINDOWS",
+		},
+		cli.StringFlag{
+			Name:   "maintenance-timezone",
+			Usage:  "timezone of the maintenance windows (e.g. Europe/Amsterdam)",
+			Value:  "Local",
+			EnvVar: "MAINTENANCE_TIMEZONE",
+		},
+		cli.DurationFlag{
+			Name:   "dampening-min-restart-interval",
+			Usage:  "minimum time between two packet-forwarder restarts or reloads (disabled when 0)",
+			EnvVar: "DAMPENING_MIN_RESTART_INTERVAL",
+		},
+		cli.IntFlag{
+			Name:   "dampening-max-restarts-per-hour",
+			Usage:  "maximum number of packet-forwarder restarts or reloads per hour (disabled when 0)",
+			EnvVar: "DAMPENING_MAX_RESTARTS_PER_HOUR",
+		},
+		cli.IntFlag{
+			Name:   "dampening-settle-polls",
+			Usage:  "number of consecutive polls the configuration must be stable before it is applied (disabled when 0)",
+			EnvVar: "DAMPENING_SETTLE_POLLS",
+		},
+		cli.BoolFlag{
+			Name:   "tx-disable",
+			Usage:  "disable tx on all radios (e.g. for receive-only gateways)",
+			EnvVar: "TX_DISABLE",
+		},
+		cli.IntFlag{
+			Name:   "tx-radio",
+			Usage:  "radio used for tx (0 or 1, when -1 and tx-disable, tx-freq-min and tx-freq-max are not set, the tx_enable of the base configuration file is kept)",
+			Value:  -1,
+			EnvVar: "TX_RADIO",
+		},
+		cli.IntFlag{
+			Name:   "tx-freq-min",
+			Usage:  "min. tx frequency in Hz (when 0, the tx_freq_min of the base configuration file is kept)",
+			EnvVar: "TX_FREQ_MIN",
+		},
+		cli.IntFlag{
+			Name:   "tx-freq-max",
+			Usage:  "max. tx frequency in Hz (when 0, the tx_freq_max of the base configuration file is kept)",
+			EnvVar: "TX_FREQ_MAX",
+		},
+		cli.IntFlag{
+			Name:   "tx-max-eirp",
+			Usage:  "max. tx power (EIRP) in dBm, tx_lut entries exceeding this power are clamped (when 0, the max. eirp of the band is used or when no band is set, the tx_lut of the base configuration file is kept)",
+			EnvVar: "TX_MAX_EIRP",
+		},
+		cli.IntFlag{
+			Name:   "tx-antenna-gain",
+			Usage:  "antenna gain in dBi (when tx-antenna-gain and tx-cable-loss are 0, the antenna_gain of the base configuration file is kept)",
+			EnvVar: "TX_ANTENNA_GAIN",
+		},
+		cli.IntFlag{
+			Name:   "tx-cable-loss",
+			Usage:  "cable loss between the concentrator and antenna in dB",
+			EnvVar: "TX_CABLE_LOSS",
+		},
+		cli.StringFlag{
+			Name:   "pf-server-address",
+			Usage:  "packet-forwarder server_address (when blank, the value of the base configuration file is kept)",
+			EnvVar: "PF_SERVER_ADDRESS",
+		},
+		cli.IntFlag{
+			Name:   "pf-serv-port-up",
+			Usage:  "packet-forwarder serv_port_up (when 0, the value of the base configuration file is kept)",
+			EnvVar: "PF_SERV_PORT_UP",
+		},
+		cli.IntFlag{
+			Name:   "pf-serv-port-down",
+			Usage:  "packet-forwarder serv_port_down (when 0, the value of the base configuration file is kept)",
+			EnvVar: "PF_SERV_PORT_DOWN",
+		},
+		cli.IntFlag{
+			Name:   "pf-keepalive-interval",
+			Usage:  "packet-forwarder keepalive_interval in seconds (when 0, the value of the base configuration file is kept)",
+			EnvVar: "PF_KEEPALIVE_INTERVAL",
+		},
+		cli.IntFlag{
+			Name:   "pf-stat-interval",
+			Usage:  "packet-forwarder stat_interval in seconds (when 0, the value of the base configuration file is kept)",
+			EnvVar: "PF_STAT_INTERVAL",
+		},
+		cli.IntFlag{
+			Name:   "pf-push-timeout-ms",
+			Usage:  "packet-forwarder push_timeout_ms (when 0, the value of the base configuration file is kept)",
+			EnvVar: "PF_PUSH_TIMEOUT_MS",
+		},
+		cli.StringFlag{
+			Name:   "pf-forward-crc-valid",
+			Usage:  "packet-forwarder forward_crc_valid, true or false (when blank, the value of the base configuration file is kept)",
+			EnvVar: "PF_FORWARD_CRC_VALID",
+		},
+		cli.StringFlag{
+			Name:   "pf-forward-crc-error",
+			Usage:  "packet-forwarder forward_crc_error, true or false (when blank, the value of the base configuration file is kept)",
+			EnvVar: "PF_FORWARD_CRC_ERROR",
+		},
+		cli.StringFlag{
+			Name:   "pf-forward-crc-disabled",
+			Usage:  "packet-forwarder forward_crc_disabled, true or false (when blank, the value of the base configuration file is kept)",
+			EnvVar: "PF_FORWARD_CRC_DISABLED",
+		},
+		cli.BoolFlag{
+			Name:   "beacon-enable",
+			Usage:  "enable the class-b beacon (the beacon options left 0 default to the beacon settings of the band)",
+			EnvVar: "BEACON_ENABLE",
+		},
+		cli.IntFlag{
+			Name:   "beacon-period",
+			Usage:  "beacon period in seconds (default: 128 when enabled)",
+			EnvVar: "BEACON_PERIOD",
+		},
+		cli.IntFlag{
+			Name:   "beacon-freq",
+			Usage:  "beacon frequency in Hz (first beacon channel in case of frequency hopping)",
+			EnvVar: "BEACON_FREQ",
+		},
+		cli.IntFlag{
+			Name:   "beacon-freq-nb",
+			Usage:  "number of beacon channels (frequency hopping)",
+			EnvVar: "BEACON_FREQ_NB",
+		},
+		cli.IntFlag{
+			Name:   "beacon-freq-step",
+			Usage:  "step between the beacon channels in Hz (frequency hopping)",
+			EnvVar: "BEACON_FREQ_STEP",
+		},
+		cli.IntFlag{
+			Name:   "beacon-spread-factor",
+			Usage:  "beacon spread-factor",
+			EnvVar: "BEACON_SPREAD_FACTOR",
+		},
+		cli.IntFlag{
+			Name:   "beacon-bandwidth",
+			Usage:  "beacon bandwidth in Hz",
+			EnvVar: "BEACON_BANDWIDTH",
+		},
+		cli.IntFlag{
+			Name:   "beacon-power",
+			Usage:  "beacon tx power in dBm",
+			EnvVar: "BEACON_POWER",
+		},
+		cli.IntFlag{
+			Name:   "beacon-info-desc",
+			Usage:  "beacon information descriptor",
+			EnvVar: "BEACON_INFO_DESC",
+		},
+		cli.StringFlag{
+			Name:   "location-gps-tty-path",
+			Usage:  "path to the gps tty, must exist (e.g. /dev/ttyAMA0, when blank the gps_tty_path of the base configuration file is kept)",
+			EnvVar: "LOCATION_GPS_TTY_PATH",
+		},
+		cli.BoolFlag{
+			Name:   "location-gps-disable",
+			Usage:  "disable the gps (removes the gps_tty_path)",
+			EnvVar: "LOCATION_GPS_DISABLE",
+		},
+		cli.BoolFlag{
+			Name:   "location-fake-gps",
+			Usage:  "use the reference location as fake gps location",
+			EnvVar: "LOCATION_FAKE_GPS",
+		},
+		cli.StringFlag{
+			Name:   "location-latitude",
+			Usage:  "reference latitude of the gateway (when blank the ref_latitude of the base configuration file is kept)",
+			EnvVar: "LOCATION_LATITUDE",
+		},
+		cli.StringFlag{
+			Name:   "location-longitude",
+			Usage:  "reference longitude of the gateway (when blank the ref_longitude of the base configuration file is kept)",
+			EnvVar: "LOCATION_LONGITUDE",
+		},
+		cli.StringFlag{
+			Name:   "location-altitude",
+			Usage:  "reference altitude of the gateway in meters (when blank the ref_altitude of the base configuration file is kept)",
+			EnvVar: "LOCATION_ALTITUDE",
+		},
+		cli.BoolFlag{
+			Name:   "lbt-enable",
+			Usage:  "enable listen-before-talk, covering all tx frequencies of the channel-plan (required in Japan and Korea)",
+			EnvVar: "LBT_ENABLE",
+		},
+		cli.IntFlag{
+			Name:   "lbt-rssi-target",
+			Usage:  "lbt rssi target in dBm (when 0, the default of the band is used)",
+			EnvVar: "LBT_RSSI_TARGET",
+		},
+		cli.IntFlag{
+			Name:   "lbt-scan-time",
+			Usage:  "lbt scan time in µs, 128 or 5000 (when 0, the default of the band is used)",
+			EnvVar: "LBT_SCAN_TIME",
+		},
+		cli.StringFlag{
+			Name:   "radio-0-type",
+			Usage:  "type of radio_0, SX1255, SX1257 or SX1250 (when blank, the type of the base configuration file is used)",
+			EnvVar: "RADIO_0_TYPE",
+		},
+		cli.IntFlag{
+			Name:   "radio-0-freq-min",
+			Usage:  "min. frequency of radio_0 in Hz, e.g. because of the rf front-end or saw filter (when 0, the range of the radio type is used)",
+			EnvVar: "RADIO_0_FREQ_MIN",
+		},
+		cli.IntFlag{
+			Name:   "radio-0-freq-max",
+			Usage:  "max. frequency of radio_0 in Hz (when 0, the range of the radio type is used)",
+			EnvVar: "RADIO_0_FREQ_MAX",
+		},
+		cli.StringFlag{
+			Name:   "radio-1-type",
+			Usage:  "type of radio_1, SX1255, SX1257 or SX1250 (when blank, the type of the base configuration file is used)",
+			EnvVar: "RADIO_1_TYPE",
+		},
+		cli.IntFlag{
+			Name:   "radio-1-freq-min",
+			Usage:  "min. frequency of radio_1 in Hz, e.g. because of the rf front-end or saw filter (when 0, the range of the radio type is used)",
+			EnvVar: "RADIO_1_FREQ_MIN",
+		},
+		cli.IntFlag{
+			Name:   "radio-1-freq-max",
+			Usage:  "max. frequency of radio_1 in Hz (when 0, the range of the radio type is used)",
+			EnvVar: "RADIO_1_FREQ_MAX",
+		},
+		cli.StringFlag{
+			Name:   "band",
+			Usage:  "lorawan band of the gateway, used for the max. tx power, beacon and lbt defaults (optional, e.g. EU_863_870, US_902_928)",
+			EnvVar: "BAND",
+		},
+		cli.BoolFlag{
+			Name:   "dwell-time-400ms",
+			Usage:  "the 400ms dwell time limitation applies (AS_923 only, e.g. in Japan)",
+			EnvVar: "DWELL_TIME_400MS",
+		},
+		cli.StringFlag{
+			Name:   "report-url",
+			Usage:  "url to post the outcome of each configuration update to (e.g. https://ns.example.com/api/gateways/report, disabled when blank)",
+			EnvVar: "REPORT_URL",
+		},
+		cli.StringFlag{
+			Name:   "report-token",
+			Usage:  "bearer token to authenticate the reports with, only sent over https (optional)",
+			EnvVar: "REPORT_TOKEN",
+		},
+		cli.BoolFlag{
+			Name:   "report-gw-jwt",
+			Usage:  "authenticate the reports with the jwt token of the gateway-server client (only when the report url is served by the gateway-server)",
+			EnvVar: "REPORT_GW_JWT",
+		},
+		cli.DurationFlag{
+			Name:   "report-timeout",
+			Usage:  "timeout of posting a report",
+			Value:  10 * time.Second,
+			EnvVar: "REPORT_TIMEOUT",
+		},
+		cli.StringFlag{
+			Name:   "hook-command",
+			Usage:  "command to execute on configuration lifecycle events, the event is passed as json on stdin (disabled when blank)",
+			EnvVar: "HOOK_COMMAND",
+		},
+		cli.StringFlag{
+			Name:   "hook-url",
+			Usage:  "url to post configuration lifecycle events to (disabled when blank)",
+			EnvVar: "HOOK_URL",
+		},
+		cli.StringFlag{
+			Name:   "hook-token",
+			Usage:  "bearer token to authenticate the webhook with, only sent over https (optional)",
+			EnvVar: "HOOK_TOKEN",
+		},
+		cli.StringFlag{
+			Name:   "hook-events",
+			Usage:  "comma-separated list of events to fire (pre-apply, post-apply, apply-failed, rollback, server-unreachable, all events when blank)",
+			EnvVar: "HOOK_EVENTS",
+		},
+		cli.DurationFlag{
+			Name:   "hook-timeout",
+			Usage:  "timeout of each hook",
+			Value:  10 * time.Second,
+			EnvVar: "HOOK_TIMEOUT",
+		},
+		cli.StringFlag{
+			Name:   "metrics-bind",
+			Usage:  "ip:port to expose the prometheus metrics and status (/status) on (e.g. 0.0.0.0:8070, disabled when blank)",
+			EnvVar: "METRICS_BIND",
+		},
 	}
 	app.Run(os.Args)
 }
@@ -0,0 +1,187 @@
+package gwclient
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Balancing strategies.
+const (
+	// RoundRobin distributes the requests over the healthy endpoints.
+	RoundRobin = "round-robin"
+
+	// Failover always uses the first healthy endpoint (in the configured
+	// or SRV priority order).
+	Failover = "failover"
+)
+
+// endpoint holds a single gateway-server endpoint.
+type endpoint struct {
+	addr           string
+	conn           *grpc.ClientConn
+	client         gw.GatewayClient
+	unhealthyUntil time.Time
+}
+
+// FailoverClient implements gw.GatewayClient using multiple gateway-server
+// endpoints. When a request to an endpoint fails with a (possibly) transient
+// error, the endpoint is marked unhealthy for the retry interval and the
+// request is retried on the next endpoint.
+type FailoverClient struct {
+	sync.Mutex
+	endpoints     []*endpoint
+	balancing     string
+	retryInterval time.Duration
+	next          int
+}
+
+// NewFailoverClient dials the given endpoints (using the given dial options)
+// and returns a new FailoverClient.
+func NewFailoverClient(addrs []string, balancing string, retryInterval time.Duration, opts ...grpc.DialOption) (*FailoverClient, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("at least one gateway-server endpoint is required")
+	}
+
+	switch balancing {
+	case RoundRobin, Failover:
+	default:
+		return nil, fmt.Errorf("invalid balancing strategy: %s", balancing)
+	}
+
+	c := FailoverClient{
+		balancing:     balancing,
+		retryInterval: retryInterval,
+	}
+
+	for _, addr := range addrs {
+		conn, err := grpc.Dial(addr, opts...)
+		if err != nil {
+			c.Close()
+			return nil, errors.Wrapf(err, "dial %s error", addr)
+		}
+
+		c.endpoints = append(c.endpoints, &endpoint{
+			addr:   addr,
+			conn:   conn,
+			client: gw.NewGatewayClient(conn),
+		})
+	}
+
+	return &c, nil
+}
+
+// GetConfiguration returns the gateway configuration from the first
+// endpoint that does not fail.
+func (c *FailoverClient) GetConfiguration(ctx context.Context, req *gw.GetConfigurationRequest, opts ...grpc.CallOption) (*gw.GetConfigurationResponse, error) {
+	var err error
+
+	for _, ep := range c.candidates() {
+		var resp *gw.GetConfigurationResponse
+		resp, err = ep.client.GetConfiguration(ctx, req, opts...)
+		if err == nil || !isRetryable(err) {
+			return resp, err
+		}
+
+		log.WithFields(log.Fields{
+			"server":         ep.addr,
+			"retry_interval": c.retryInterval,
+		}).Warningf("gateway-server request error, marking server unhealthy: %s", err)
+		c.markUnhealthy(ep)
+	}
+
+	return nil, err
+}
+
+// Close closes the connections to all endpoints.
+func (c *FailoverClient) Close() error {
+	var err error
+	for _, ep := range c.endpoints {
+		if e := ep.conn.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// candidates returns the endpoints in the order they must be tried. Healthy
+// endpoints are returned first (starting at the round-robin offset), then
+// the unhealthy endpoints as last resort.
+func (c *FailoverClient) candidates() []*endpoint {
+	c.Lock()
+	defer c.Unlock()
+
+	offset := 0
+	if c.balancing == RoundRobin {
+		offset = c.next
+		c.next = (c.next + 1) % len(c.endpoints)
+	}
+
+	var healthy, unhealthy []*endpoint
+	now := time.Now()
+	for i := range c.endpoints {
+		ep := c.endpoints[(offset+i)%len(c.endpoints)]
+		if now.Before(ep.unhealthyUntil) {
+			unhealthy = append(unhealthy, ep)
+		} else {
+			healthy = append(healthy, ep)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+func (c *FailoverClient) markUnhealthy(ep *endpoint) {
+	c.Lock()
+	defer c.Unlock()
+	ep.unhealthyUntil = time.Now().Add(c.retryInterval)
+}
+
+// isRetryable returns true when the error could be specific to the
+// endpoint. Errors like Unauthenticated or NotFound would be returned by
+// every endpoint.
+func isRetryable(err error) bool {
+	switch grpc.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.Internal, codes.Unknown, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseEndpoints returns the endpoints from the given comma separated list
+// of hostname:port endpoints.
+func ParseEndpoints(s string) []string {
+	var out []string
+	for _, addr := range strings.Split(s, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
+// LookupSRVEndpoints returns the endpoints for the given DNS SRV record
+// (e.g. _loraserver-gw._tcp.example.com), ordered by priority and randomized
+// by weight.
+func LookupSRVEndpoints(name string) ([]string, error) {
+	_, records, err := net.LookupSRV("", "", name)
+	if err != nil {
+		return nil, errors.Wrap(err, "lookup srv error")
+	}
+
+	var out []string
+	for _, r := range records {
+		out = append(out, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), fmt.Sprintf("%d", r.Port)))
+	}
+	return out, nil
+}
@@ -0,0 +1,164 @@
+package gwclient
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/brocaar/loraserver/api/gw"
+)
+
+// testGatewayServer implements a fake gw.GatewayServer, returning its name
+// as UpdatedAt.
+type testGatewayServer struct {
+	name   string
+	err    error
+	server *grpc.Server
+	addr   string
+}
+
+func (s *testGatewayServer) GetConfiguration(ctx context.Context, req *gw.GetConfigurationRequest) (*gw.GetConfigurationResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &gw.GetConfigurationResponse{UpdatedAt: s.name}, nil
+}
+
+func newTestGatewayServer(name string) (*testGatewayServer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := testGatewayServer{
+		name:   name,
+		server: grpc.NewServer(),
+		addr:   ln.Addr().String(),
+	}
+	gw.RegisterGatewayServer(s.server, &s)
+	go s.server.Serve(ln)
+
+	return &s, nil
+}
+
+func TestFailoverClient(t *testing.T) {
+	Convey("Given three fake gateway-servers", t, func() {
+		var servers []*testGatewayServer
+		var addrs []string
+		for _, name := range []string{"a", "b", "c"} {
+			s, err := newTestGatewayServer(name)
+			So(err, ShouldBeNil)
+			defer s.server.Stop()
+			servers = append(servers, s)
+			addrs = append(addrs, s.addr)
+		}
+
+		getConfiguration := func(c *FailoverClient) (string, error) {
+			resp, err := c.GetConfiguration(context.Background(), &gw.GetConfigurationRequest{})
+			if err != nil {
+				return "", err
+			}
+			return resp.UpdatedAt, nil
+		}
+
+		Convey("Given a round-robin client", func() {
+			client, err := NewFailoverClient(addrs, RoundRobin, time.Minute, grpc.WithInsecure())
+			So(err, ShouldBeNil)
+			defer client.Close()
+
+			Convey("Then the requests are distributed over all servers", func() {
+				for _, expected := range []string{"a", "b", "c", "a"} {
+					name, err := getConfiguration(client)
+					So(err, ShouldBeNil)
+					So(name, ShouldEqual, expected)
+				}
+			})
+
+			Convey("When one server is down", func() {
+				servers[1].server.Stop()
+
+				Convey("Then all requests succeed and the server is skipped", func() {
+					var names []string
+					for i := 0; i < 4; i++ {
+						name, err := getConfiguration(client)
+						So(err, ShouldBeNil)
+						names = append(names, name)
+					}
+					So(names, ShouldResemble, []string{"a", "c", "c", "a"})
+				})
+			})
+
+			Convey("When one server returns an Unavailable error", func() {
+				servers[0].err = grpc.Errorf(codes.Unavailable, "unavailable")
+
+				Convey("Then the request is retried on the next server", func() {
+					name, err := getConfiguration(client)
+					So(err, ShouldBeNil)
+					So(name, ShouldEqual, "b")
+				})
+			})
+
+			Convey("When a server returns an Unauthenticated error", func() {
+				servers[0].err = grpc.Errorf(codes.Unauthenticated, "invalid token")
+
+				Convey("Then the error is returned without retrying", func() {
+					_, err := getConfiguration(client)
+					So(grpc.Code(err), ShouldEqual, codes.Unauthenticated)
+				})
+			})
+
+			Convey("When all servers are down", func() {
+				for _, s := range servers {
+					s.server.Stop()
+				}
+
+				Convey("Then an error is returned", func() {
+					_, err := getConfiguration(client)
+					So(err, ShouldNotBeNil)
+				})
+			})
+		})
+
+		Convey("Given a failover client", func() {
+			client, err := NewFailoverClient(addrs, Failover, time.Minute, grpc.WithInsecure())
+			So(err, ShouldBeNil)
+			defer client.Close()
+
+			Convey("Then the first server is always used", func() {
+				for i := 0; i < 3; i++ {
+					name, err := getConfiguration(client)
+					So(err, ShouldBeNil)
+					So(name, ShouldEqual, "a")
+				}
+			})
+
+			Convey("When the first server is down", func() {
+				servers[0].server.Stop()
+
+				Convey("Then the second server is used", func() {
+					for i := 0; i < 3; i++ {
+						name, err := getConfiguration(client)
+						So(err, ShouldBeNil)
+						So(name, ShouldEqual, "b")
+					}
+				})
+			})
+		})
+
+		Convey("Then an invalid balancing strategy returns an error", func() {
+			_, err := NewFailoverClient(addrs, "random", time.Minute, grpc.WithInsecure())
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a comma separated list of endpoints", t, func() {
+		Convey("Then ParseEndpoints returns the endpoints", func() {
+			So(ParseEndpoints("ns1:8002, ns2:8002,,"), ShouldResemble, []string{"ns1:8002", "ns2:8002"})
+		})
+	})
+}
@@ -0,0 +1,175 @@
+// Package gwclient implements the gateway-server client, its credentials
+// and the failover over multiple gateway-server endpoints.
+package gwclient
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// jwtExpiryWarning defines the duration before the token expiry from which
+// a warning will be logged on (re)loading the token.
+const jwtExpiryWarning = 7 * 24 * time.Hour
+
+var jwtExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "lora_channel_manager_jwt_token_expiry_timestamp_seconds",
+	Help: "Expiry (exp claim) of the jwt token used by the gateway-server client, as unix timestamp (0 when unknown).",
+})
+
+func init() {
+	prometheus.MustRegister(jwtExpiry)
+}
+
+// JWTCredentials implements the grpc credentials.PerRPCCredentials interface.
+// When a token file is set, the token is read from this file and re-read
+// each time the file has been modified.
+type JWTCredentials struct {
+	sync.Mutex
+	token         string
+	tokenFile     string
+	modTime       time.Time
+	allowInsecure bool
+}
+
+// NewJWTCredentials creates a new JWTCredentials. When tokenFile is set, it
+// takes precedence over the given token. Unless allowInsecure is set, the
+// token can only be sent over a secure (TLS) connection.
+func NewJWTCredentials(token, tokenFile string, allowInsecure bool) (*JWTCredentials, error) {
+	j := JWTCredentials{
+		tokenFile:     tokenFile,
+		allowInsecure: allowInsecure,
+	}
+
+	if tokenFile == "" {
+		j.setToken(token)
+		return &j, nil
+	}
+
+	if err := j.reloadTokenFile(); err != nil {
+		return nil, err
+	}
+
+	return &j, nil
+}
+
+// GetRequestMetadata returns the authorization metadata. In case the token
+// file has been modified, the token is reloaded first.
+func (j *JWTCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.tokenFile != "" {
+		if err := j.reloadTokenFile(); err != nil {
+			// keep using the previous token
+			log.WithField("path", j.tokenFile).Errorf("reload jwt token file error: %s", err)
+		}
+	}
+
+	return map[string]string{
+		"authorization": j.token,
+	}, nil
+}
+
+// RequireTransportSecurity returns true unless sending the token over an
+// insecure connection has been explicitly allowed.
+func (j *JWTCredentials) RequireTransportSecurity() bool {
+	return !j.allowInsecure
+}
+
+// reloadTokenFile reads the token file when it has been modified since the
+// last read.
+func (j *JWTCredentials) reloadTokenFile() error {
+	fi, err := os.Stat(j.tokenFile)
+	if err != nil {
+		return errors.Wrap(err, "stat token file error")
+	}
+
+	if fi.ModTime().Equal(j.modTime) {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(j.tokenFile)
+	if err != nil {
+		return errors.Wrap(err, "read token file error")
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return errors.New("token file is empty")
+	}
+
+	j.setToken(token)
+	j.modTime = fi.ModTime()
+	log.WithField("path", j.tokenFile).Info("jwt token loaded from file")
+
+	return nil
+}
+
+// setToken sets the token and logs / exposes its expiry.
+func (j *JWTCredentials) setToken(token string) {
+	j.token = token
+
+	exp, err := jwtExpiryTime(token)
+	if err != nil {
+		jwtExpiry.Set(0)
+		log.Warningf("get jwt token expiry error: %s", err)
+		return
+	}
+
+	if exp.IsZero() {
+		jwtExpiry.Set(0)
+		return
+	}
+
+	jwtExpiry.Set(float64(exp.Unix()))
+	logger := log.WithField("expires_at", exp)
+
+	switch {
+	case time.Now().After(exp):
+		logger.Error("jwt token has expired")
+	case time.Now().Add(jwtExpiryWarning).After(exp):
+		logger.Warning("jwt token is about to expire")
+	default:
+		logger.Info("jwt token expiry")
+	}
+}
+
+// jwtExpiryTime returns the expiry (exp claim) of the given token. When the
+// token does not contain an exp claim, the zero time is returned.
+// Note that the token signature is not validated, this is done by the
+// gateway-server.
+func jwtExpiryTime(token string) (time.Time, error) {
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("token must contain three parts")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "decode claims error")
+	}
+
+	if err = json.Unmarshal(b, &claims); err != nil {
+		return time.Time{}, errors.Wrap(err, "unmarshal claims error")
+	}
+
+	if claims.Exp == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,99 @@
+package gwclient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	log.SetLevel(log.ErrorLevel)
+}
+
+func testToken(claims string) string {
+	return fmt.Sprintf("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.%s.c2lnbmF0dXJl", base64.RawURLEncoding.EncodeToString([]byte(claims)))
+}
+
+func TestJWTCredentials(t *testing.T) {
+	Convey("Given a token with exp claim", t, func() {
+		exp := time.Now().Add(time.Hour).Truncate(time.Second)
+		token := testToken(fmt.Sprintf(`{"iss":"lora-app-server","exp":%d}`, exp.Unix()))
+
+		Convey("Then jwtExpiryTime returns the expiry", func() {
+			ts, err := jwtExpiryTime(token)
+			So(err, ShouldBeNil)
+			So(ts.Equal(exp), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a token without exp claim", t, func() {
+		token := testToken(`{"iss":"lora-app-server"}`)
+
+		Convey("Then jwtExpiryTime returns the zero time", func() {
+			ts, err := jwtExpiryTime(token)
+			So(err, ShouldBeNil)
+			So(ts.IsZero(), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a static token", t, func() {
+		creds, err := NewJWTCredentials("foo", "", false)
+		So(err, ShouldBeNil)
+
+		Convey("Then transport security is required", func() {
+			So(creds.RequireTransportSecurity(), ShouldBeTrue)
+		})
+
+		Convey("Then the token is used as authorization metadata", func() {
+			md, err := creds.GetRequestMetadata(context.Background())
+			So(err, ShouldBeNil)
+			So(md, ShouldResemble, map[string]string{"authorization": "foo"})
+		})
+	})
+
+	Convey("Given a token file", t, func() {
+		tempDir, err := ioutil.TempDir("", "test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+
+		tokenFile := filepath.Join(tempDir, "token")
+		So(ioutil.WriteFile(tokenFile, []byte("foo\n"), 0600), ShouldBeNil)
+
+		creds, err := NewJWTCredentials("ignored", tokenFile, true)
+		So(err, ShouldBeNil)
+		So(creds.RequireTransportSecurity(), ShouldBeFalse)
+
+		md, err := creds.GetRequestMetadata(context.Background())
+		So(err, ShouldBeNil)
+		So(md["authorization"], ShouldEqual, "foo")
+
+		Convey("When the token file is updated", func() {
+			So(ioutil.WriteFile(tokenFile, []byte("bar"), 0600), ShouldBeNil)
+			So(os.Chtimes(tokenFile, time.Now(), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			Convey("Then the new token is used", func() {
+				md, err := creds.GetRequestMetadata(context.Background())
+				So(err, ShouldBeNil)
+				So(md["authorization"], ShouldEqual, "bar")
+			})
+		})
+
+		Convey("When the token file is removed", func() {
+			So(os.Remove(tokenFile), ShouldBeNil)
+
+			Convey("Then the previous token is still used", func() {
+				md, err := creds.GetRequestMetadata(context.Background())
+				So(err, ShouldBeNil)
+				So(md["authorization"], ShouldEqual, "foo")
+			})
+		})
+	})
+}
@@ -0,0 +1,165 @@
+package gwclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+// tlsVersions maps the supported minimum TLS version strings.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+}
+
+// TLSOptions contains the TLS options of the gateway-server client.
+type TLSOptions struct {
+	// CACert contains the path to the CA certificate (optional).
+	CACert string
+
+	// SystemRoots adds the system root CAs to the pool containing CACert.
+	// When CACert is not set, the system root CAs are always used.
+	SystemRoots bool
+
+	// TLSCert and TLSKey contain the path to the client certificate and
+	// key (optional). These are reloaded on change.
+	TLSCert string
+	TLSKey  string
+
+	// ServerName overrides the name used to verify the server certificate
+	// (e.g. when dialing by IP).
+	ServerName string
+
+	// MinVersion contains the minimum TLS version (e.g. 1.2).
+	MinVersion string
+}
+
+// NewTLSConfig returns the tls.Config for the given options. The client
+// certificate (if configured) is loaded on each handshake in case the
+// certificate or key file has been modified, so that short-lived client
+// certificates can be rotated without restarting.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	conf := tls.Config{
+		ServerName: opts.ServerName,
+	}
+
+	if opts.MinVersion != "" {
+		v, ok := tlsVersions[opts.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid minimum tls version: %s", opts.MinVersion)
+		}
+		conf.MinVersion = v
+	}
+
+	if opts.CACert != "" {
+		pool, err := newCertPool(opts.CACert, opts.SystemRoots)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+
+	if opts.TLSCert != "" || opts.TLSKey != "" {
+		kp := keyPair{
+			certFile: opts.TLSCert,
+			keyFile:  opts.TLSKey,
+		}
+		if err := kp.reload(); err != nil {
+			return nil, err
+		}
+		conf.GetClientCertificate = kp.getClientCertificate
+	}
+
+	return &conf, nil
+}
+
+// newCertPool returns a cert-pool containing the given CA certificate and
+// optionally the system root CAs.
+func newCertPool(caCert string, systemRoots bool) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if systemRoots {
+		sp, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, errors.Wrap(err, "load system cert-pool error")
+		}
+		pool = sp
+	}
+
+	b, err := ioutil.ReadFile(caCert)
+	if err != nil {
+		return nil, errors.Wrap(err, "read ca cert error")
+	}
+
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in ca cert %s", caCert)
+	}
+
+	return pool, nil
+}
+
+// keyPair holds the client certificate and reloads it when the certificate
+// or key file has been modified.
+type keyPair struct {
+	sync.Mutex
+	certFile    string
+	keyFile     string
+	certModTime time.Time
+	keyModTime  time.Time
+	cert        *tls.Certificate
+}
+
+func (k *keyPair) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	k.Lock()
+	defer k.Unlock()
+
+	if err := k.reload(); err != nil {
+		// keep using the previous certificate
+		log.WithFields(log.Fields{
+			"cert": k.certFile,
+			"key":  k.keyFile,
+		}).Errorf("reload key-pair error: %s", err)
+	}
+
+	return k.cert, nil
+}
+
+// reload loads the key-pair when the certificate or key file has been
+// modified since the last load.
+func (k *keyPair) reload() error {
+	certInfo, err := os.Stat(k.certFile)
+	if err != nil {
+		return errors.Wrap(err, "stat tls cert error")
+	}
+	keyInfo, err := os.Stat(k.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "stat tls key error")
+	}
+
+	if certInfo.ModTime().Equal(k.certModTime) && keyInfo.ModTime().Equal(k.keyModTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "load key-pair error")
+	}
+
+	k.cert = &cert
+	k.certModTime = certInfo.ModTime()
+	k.keyModTime = keyInfo.ModTime()
+
+	log.WithFields(log.Fields{
+		"cert": k.certFile,
+		"key":  k.keyFile,
+	}).Info("tls key-pair loaded")
+
+	return nil
+}
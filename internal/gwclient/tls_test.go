@@ -0,0 +1,200 @@
+package gwclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert generates a certificate for the given common-name. When
+// parent is nil, a self-signed CA certificate is generated.
+func newTestCert(cn string, serial int64, parent *testCert) (*testCert, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	parentCert := &tmpl
+	parentKey := key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		parentCert = parent.cert
+		parentKey = parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+func TestTLSConfig(t *testing.T) {
+	Convey("Given a CA, server and client certificate", t, func() {
+		tempDir, err := ioutil.TempDir("", "test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+
+		ca, err := newTestCert("ca", 1, nil)
+		So(err, ShouldBeNil)
+		server, err := newTestCert("gateway-server.example.com", 2, ca)
+		So(err, ShouldBeNil)
+		client, err := newTestCert("client", 3, ca)
+		So(err, ShouldBeNil)
+
+		caFile := filepath.Join(tempDir, "ca.pem")
+		certFile := filepath.Join(tempDir, "cert.pem")
+		keyFile := filepath.Join(tempDir, "key.pem")
+		So(ioutil.WriteFile(caFile, ca.certPEM, 0600), ShouldBeNil)
+		So(ioutil.WriteFile(certFile, client.certPEM, 0600), ShouldBeNil)
+		So(ioutil.WriteFile(keyFile, client.keyPEM, 0600), ShouldBeNil)
+
+		opts := TLSOptions{
+			CACert:     caFile,
+			TLSCert:    certFile,
+			TLSKey:     keyFile,
+			ServerName: "gateway-server.example.com",
+			MinVersion: "1.2",
+		}
+
+		Convey("Then NewTLSConfig returns the expected config", func() {
+			conf, err := NewTLSConfig(opts)
+			So(err, ShouldBeNil)
+			So(conf.ServerName, ShouldEqual, "gateway-server.example.com")
+			So(conf.MinVersion, ShouldEqual, uint16(tls.VersionTLS12))
+			So(conf.GetClientCertificate, ShouldNotBeNil)
+		})
+
+		Convey("Then an invalid minimum tls version returns an error", func() {
+			opts.MinVersion = "0.9"
+			_, err := NewTLSConfig(opts)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Then a missing key file returns an error", func() {
+			opts.TLSKey = filepath.Join(tempDir, "missing.pem")
+			_, err := NewTLSConfig(opts)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Then the system roots can be combined with the CA cert", func() {
+			opts.SystemRoots = true
+			_, err := NewTLSConfig(opts)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Given a TLS server requiring a client certificate (listening on an IP)", func() {
+			serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+			So(err, ShouldBeNil)
+			clientCAs := x509.NewCertPool()
+			clientCAs.AddCert(ca.cert)
+
+			ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+				Certificates: []tls.Certificate{serverCert},
+				ClientCAs:    clientCAs,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+			})
+			So(err, ShouldBeNil)
+			defer ln.Close()
+
+			peerSerials := make(chan int64, 10)
+			go func() {
+				for {
+					conn, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					tlsConn := conn.(*tls.Conn)
+					if err := tlsConn.Handshake(); err == nil {
+						peerSerials <- tlsConn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+					}
+					conn.Close()
+				}
+			}()
+
+			conf, err := NewTLSConfig(opts)
+			So(err, ShouldBeNil)
+
+			dial := func() error {
+				conn, err := tls.Dial("tcp", ln.Addr().String(), conf)
+				if err != nil {
+					return err
+				}
+				return conn.Close()
+			}
+
+			Convey("Then the handshake succeeds using the server-name override", func() {
+				So(dial(), ShouldBeNil)
+				So(<-peerSerials, ShouldEqual, 3)
+			})
+
+			Convey("Then without server-name override the handshake fails", func() {
+				opts.ServerName = ""
+				conf, err := NewTLSConfig(opts)
+				So(err, ShouldBeNil)
+				_, err = tls.Dial("tcp", ln.Addr().String(), conf)
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("When the client certificate is rotated", func() {
+				rotated, err := newTestCert("client", 4, ca)
+				So(err, ShouldBeNil)
+				So(ioutil.WriteFile(certFile, rotated.certPEM, 0600), ShouldBeNil)
+				So(ioutil.WriteFile(keyFile, rotated.keyPEM, 0600), ShouldBeNil)
+				future := time.Now().Add(time.Minute)
+				So(os.Chtimes(certFile, future, future), ShouldBeNil)
+				So(os.Chtimes(keyFile, future, future), ShouldBeNil)
+
+				Convey("Then the new certificate is used without re-creating the config", func() {
+					So(dial(), ShouldBeNil)
+					So(<-peerSerials, ShouldEqual, 4)
+				})
+			})
+		})
+	})
+}
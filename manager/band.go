@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/brocaar/lorawan/band"
+)
+
+// bandMaxEIRP defines the max. EIRP (dBm, rounded down) of the gateway per
+// band, based on the LoRaWAN Regional Parameters. As the lorawan/band
+// package does not define the max. EIRP, it is defined here. For the bands
+// defining sub-bands (see bandSubBands), the max. EIRP of the sub-bands is
+// used instead.
+var bandMaxEIRP = map[band.Name]int{
+	band.AS_923:     16,
+	band.AU_915_928: 30,
+	band.CN_470_510: 19,
+	band.IN_865_867: 30,
+	band.KR_920_923: 23,
+	band.US_902_928: 30,
+}
+
+// getBandMaxEIRP returns the max. EIRP (dBm) for the given band. For the
+// bands defining sub-bands, this is the highest max. EIRP of the sub-bands
+// overlapping the given TX frequency range (all sub-bands when the range is
+// not set), e.g. 27 dBm for the EU_863_870 RX2 and beacon frequency
+// (869.525 MHz). As the tx_lut applies to all frequencies, limiting the TX
+// power per sub-band is up to the network-server.
+func getBandMaxEIRP(name band.Name, freqMin, freqMax int) (int, error) {
+	if subBands, ok := bandSubBands[name]; ok {
+		var maxEIRP int
+		var found bool
+		for _, sb := range subBands {
+			if (freqMin != 0 || freqMax != 0) && (sb.maxFreq < freqMin || sb.minFreq > freqMax) {
+				continue
+			}
+			if !found || sb.maxEIRP > maxEIRP {
+				maxEIRP = sb.maxEIRP
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("tx frequency range %d - %d Hz is not within a sub-band of band %s", freqMin, freqMax, name)
+		}
+		return maxEIRP, nil
+	}
+
+	maxEIRP, ok := bandMaxEIRP[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown band: %s", name)
+	}
+	return maxEIRP, nil
+}
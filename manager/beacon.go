@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/brocaar/lorawan"
+	"github.com/brocaar/lorawan/band"
+	"github.com/pkg/errors"
+)
+
+// defaultBeaconPeriod defines the Class-B beacon period (seconds).
+const defaultBeaconPeriod = 128
+
+// bandBeacon defines the Class-B beacon frequency (or first frequency in
+// case of frequency hopping), the number of beacon channels, the step
+// between the beacon channels, the beacon data-rate and the beacon TX power
+// (dBm, the max. EIRP at the beacon frequency) per band, based on the
+// LoRaWAN Regional Parameters.
+var bandBeacon = map[band.Name]struct {
+	freq     int
+	freqNb   int
+	freqStep int
+	dataRate int
+	power    int
+}{
+	band.AS_923:     {freq: 923400000, freqNb: 1, dataRate: 3, power: 16},
+	band.AU_915_928: {freq: 923300000, freqNb: 8, freqStep: 600000, dataRate: 8, power: 30},
+	band.CN_470_510: {freq: 508300000, freqNb: 8, freqStep: 200000, dataRate: 2, power: 19},
+	band.CN_779_787: {freq: 785000000, freqNb: 1, dataRate: 3, power: 12},
+	band.EU_433:     {freq: 434665000, freqNb: 1, dataRate: 3, power: 12},
+	band.EU_863_870: {freq: 869525000, freqNb: 1, dataRate: 3, power: 27},
+	band.IN_865_867: {freq: 866550000, freqNb: 1, dataRate: 4, power: 30},
+	band.KR_920_923: {freq: 923100000, freqNb: 1, dataRate: 3, power: 23},
+	band.US_902_928: {freq: 923300000, freqNb: 8, freqStep: 600000, dataRate: 8, power: 30},
+}
+
+// BeaconConfig contains the Class-B beacon configuration of the
+// packet-forwarder (gateway_conf).
+type BeaconConfig struct {
+	// Period defines the beacon period (seconds). When 0, the beacon is
+	// disabled.
+	Period int
+
+	// Freq defines the beacon frequency (Hz). In case of frequency hopping,
+	// this is the frequency of the first beacon channel.
+	Freq int
+
+	// FreqNb and FreqStep define the number of beacon channels and the
+	// step between these channels (Hz), in case of frequency hopping.
+	FreqNb   int
+	FreqStep int
+
+	// SpreadFactor and Bandwidth (Hz) define the beacon data-rate.
+	SpreadFactor int
+	Bandwidth    int
+
+	// Power defines the beacon TX power (dBm).
+	Power int
+
+	// InfoDesc defines the beacon information descriptor.
+	InfoDesc int
+}
+
+// setBandDefaults sets the values that are not set to the defaults of the
+// given band.
+func (c *BeaconConfig) setBandDefaults(name band.Name) error {
+	b, ok := bandBeacon[name]
+	if !ok {
+		return fmt.Errorf("unknown band: %s", name)
+	}
+
+	bandConfig, err := band.GetConfig(name, false, lorawan.DwellTimeNoLimit)
+	if err != nil {
+		return errors.Wrap(err, "get band config error")
+	}
+	dr := bandConfig.DataRates[b.dataRate]
+
+	if c.Freq == 0 {
+		c.Freq = b.freq
+		c.FreqNb = b.freqNb
+		c.FreqStep = b.freqStep
+	}
+	if c.SpreadFactor == 0 {
+		c.SpreadFactor = dr.SpreadFactor
+	}
+	if c.Bandwidth == 0 {
+		c.Bandwidth = dr.Bandwidth * 1000
+	}
+	if c.Power == 0 {
+		c.Power = b.power
+	}
+
+	return nil
+}
+
+// validate validates the beacon configuration.
+func (c BeaconConfig) validate() error {
+	if c.Period < 0 {
+		return fmt.Errorf("beacon period must not be negative: %d", c.Period)
+	}
+	if c.Period == 0 {
+		return nil
+	}
+	if c.Freq == 0 {
+		return errors.New("beacon frequency must be set (or set the band)")
+	}
+	if c.FreqNb < 0 || (c.FreqNb > 1 && c.FreqStep <= 0) {
+		return fmt.Errorf("invalid beacon frequency hopping: %d channels with step %d", c.FreqNb, c.FreqStep)
+	}
+	if c.SpreadFactor < 7 || c.SpreadFactor > 12 {
+		return fmt.Errorf("invalid beacon spread-factor: %d", c.SpreadFactor)
+	}
+	switch c.Bandwidth {
+	case 125000, 250000, 500000:
+	default:
+		return fmt.Errorf("invalid beacon bandwidth: %d", c.Bandwidth)
+	}
+	return nil
+}
+
+// mergeBeaconConfig merges the beacon configuration into the given
+// gateway_conf.
+func mergeBeaconConfig(gatewayConf map[string]interface{}, c BeaconConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	gatewayConf["beacon_period"] = c.Period
+	if c.Period == 0 {
+		return nil
+	}
+
+	gatewayConf["beacon_freq_hz"] = c.Freq
+	gatewayConf["beacon_freq_nb"] = c.FreqNb
+	gatewayConf["beacon_freq_step"] = c.FreqStep
+	gatewayConf["beacon_datarate"] = c.SpreadFactor
+	gatewayConf["beacon_bw_hz"] = c.Bandwidth
+	gatewayConf["beacon_power"] = c.Power
+	gatewayConf["beacon_infodesc"] = c.InfoDesc
+
+	return nil
+}
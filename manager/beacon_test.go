@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan/band"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBeaconConfig(t *testing.T) {
+	Convey("Given a DefaultPlanner with an enabled beacon", t, func() {
+		p := DefaultPlanner{Beacon: &BeaconConfig{Period: defaultBeaconPeriod}}
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+
+		testTable := []struct {
+			Band     band.Name
+			Expected BeaconConfig
+		}{
+			{
+				Band: band.EU_863_870,
+				Expected: BeaconConfig{
+					Period:       128,
+					Freq:         869525000,
+					FreqNb:       1,
+					SpreadFactor: 9,
+					Bandwidth:    125000,
+					Power:        27,
+				},
+			},
+			{
+				Band: band.US_902_928,
+				Expected: BeaconConfig{
+					Period:       128,
+					Freq:         923300000,
+					FreqNb:       8,
+					FreqStep:     600000,
+					SpreadFactor: 12,
+					Bandwidth:    500000,
+					Power:        30,
+				},
+			},
+		}
+
+		for _, test := range testTable {
+			Convey("Then the beacon defaults to the settings of band "+string(test.Band), func() {
+				p.Band = test.Band
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Beacon, ShouldResemble, &test.Expected)
+			})
+		}
+
+		Convey("Then configured values take precedence over the band defaults", func() {
+			p.Band = band.EU_863_870
+			p.Beacon.Power = 14
+			conf, err := p.Plan(&resp)
+			So(err, ShouldBeNil)
+			So(conf.Beacon.Power, ShouldEqual, 14)
+			So(conf.Beacon.Freq, ShouldEqual, 869525000)
+		})
+
+		Convey("Then without band and frequency an error is returned", func() {
+			_, err := p.Plan(&resp)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given the test base configuration", t, func() {
+		conf, err := loadConfigFile("test/test.json")
+		So(err, ShouldBeNil)
+
+		Convey("When merging a beacon configuration", func() {
+			So(mergeBeaconConfig(conf.GatewayConf, BeaconConfig{
+				Period:       128,
+				Freq:         869525000,
+				FreqNb:       1,
+				SpreadFactor: 9,
+				Bandwidth:    125000,
+				Power:        14,
+			}), ShouldBeNil)
+
+			Convey("Then the beacon settings are set", func() {
+				So(conf.GatewayConf["beacon_period"], ShouldEqual, 128)
+				So(conf.GatewayConf["beacon_freq_hz"], ShouldEqual, 869525000)
+				So(conf.GatewayConf["beacon_freq_nb"], ShouldEqual, 1)
+				So(conf.GatewayConf["beacon_datarate"], ShouldEqual, 9)
+				So(conf.GatewayConf["beacon_bw_hz"], ShouldEqual, 125000)
+				So(conf.GatewayConf["beacon_power"], ShouldEqual, 14)
+			})
+		})
+
+		Convey("When merging a disabled beacon configuration", func() {
+			So(mergeBeaconConfig(conf.GatewayConf, BeaconConfig{}), ShouldBeNil)
+
+			Convey("Then the beacon is disabled", func() {
+				So(conf.GatewayConf["beacon_period"], ShouldEqual, 0)
+			})
+		})
+	})
+}
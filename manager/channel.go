@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/brocaar/loraserver/api/gw"
+)
+
+// Modulation defines the modulation of a channel.
+type Modulation string
+
+// Supported modulations.
+const (
+	LoRaModulation Modulation = "LORA"
+	FSKModulation  Modulation = "FSK"
+)
+
+// fskBandwidths defines the FSK bandwidths (Hz) supported by the SX1301.
+var fskBandwidths = []int{7800, 15600, 31200, 62500, 125000, 250000, 500000}
+
+// FSK bit-rate limits (bit/s) of the SX1301.
+const (
+	fskMinBitRate = 500
+	fskMaxBitRate = 250000
+)
+
+// Channel contains a channel of the channel-plan. All frequencies and
+// bandwidths are in Hz.
+type Channel struct {
+	Modulation Modulation
+
+	// Freq and Bandwidth define the center frequency and bandwidth of the
+	// channel.
+	Freq      int
+	Bandwidth int
+
+	// SpreadFactors defines the spread-factors (LoRa modulation only).
+	SpreadFactors []int
+
+	// BitRate (bit/s) and FreqDeviation define the FSK modulation
+	// parameters (FSK modulation only).
+	BitRate       int
+	FreqDeviation int
+}
+
+// channelFromGW converts the given gateway API channel (kHz bandwidth) into
+// a Channel (Hz). For FSK channels the frequency deviation defaults to half
+// the bit-rate (modulation index 1) and the bandwidth is set to the smallest
+// SX1301 FSK bandwidth covering the modulation (Carson's rule).
+func channelFromGW(c *gw.Channel) (Channel, error) {
+	out := Channel{
+		Freq:      int(c.Frequency),
+		Bandwidth: int(c.Bandwidth) * 1000,
+	}
+
+	switch c.Modulation {
+	case gw.Modulation_LORA:
+		out.Modulation = LoRaModulation
+		for _, sf := range c.SpreadFactors {
+			out.SpreadFactors = append(out.SpreadFactors, int(sf))
+		}
+	case gw.Modulation_FSK:
+		out.Modulation = FSKModulation
+		out.BitRate = int(c.BitRate)
+		out.FreqDeviation = out.BitRate / 2
+
+		required := 2*out.FreqDeviation + out.BitRate
+		if out.Bandwidth != 0 && out.Bandwidth < required {
+			return out, fmt.Errorf("fsk channel %d Hz: bandwidth of %d Hz is too small for %d bit/s (requires %d Hz)", out.Freq, out.Bandwidth, out.BitRate, required)
+		}
+		if out.Bandwidth < required {
+			out.Bandwidth = required
+		}
+		for _, bw := range fskBandwidths {
+			if bw >= out.Bandwidth {
+				out.Bandwidth = bw
+				break
+			}
+		}
+	default:
+		return out, fmt.Errorf("invalid modulation %s", c.Modulation)
+	}
+
+	return out, out.validate()
+}
+
+// validate validates the channel against the SX1301 limits.
+func (c Channel) validate() error {
+	switch c.Modulation {
+	case LoRaModulation:
+		if _, ok := radioBandwidthPerChannelBandwidth[c.Bandwidth]; !ok {
+			return fmt.Errorf("lora channel %d Hz: invalid bandwidth %d Hz", c.Freq, c.Bandwidth)
+		}
+		if len(c.SpreadFactors) == 0 {
+			return fmt.Errorf("lora channel %d Hz: no spread-factors", c.Freq)
+		}
+		for _, sf := range c.SpreadFactors {
+			if sf < 7 || sf > 12 {
+				return fmt.Errorf("lora channel %d Hz: invalid spread-factor %d", c.Freq, sf)
+			}
+		}
+	case FSKModulation:
+		if c.BitRate < fskMinBitRate || c.BitRate > fskMaxBitRate {
+			return fmt.Errorf("fsk channel %d Hz: bit-rate %d bit/s is outside the supported range (%d - %d bit/s)", c.Freq, c.BitRate, fskMinBitRate, fskMaxBitRate)
+		}
+		var ok bool
+		for _, bw := range fskBandwidths {
+			if bw == c.Bandwidth {
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("fsk channel %d Hz: invalid bandwidth %d Hz", c.Freq, c.Bandwidth)
+		}
+	default:
+		return fmt.Errorf("invalid modulation %s", c.Modulation)
+	}
+	return nil
+}
+
+// minFreq and maxFreq return the lower and upper edge of the channel.
+func (c Channel) minFreq() int { return c.Freq - c.Bandwidth/2 }
+func (c Channel) maxFreq() int { return c.Freq + c.Bandwidth/2 }
+
+// radioBandwidth returns the bandwidth that a single radio can cover for
+// the channel. The SX1301 HAL applies the same IF limits to the FSK channel
+// as to the LoRa channels, based on the channel bandwidth: FSK bandwidths up
+// to 125 kHz use the radio bandwidth of the 125 kHz channels
+// (defaultRadioBandwidth).
+func (c Channel) radioBandwidth() int {
+	if bw, ok := radioBandwidthPerChannelBandwidth[c.Bandwidth]; ok {
+		return bw
+	}
+	return defaultRadioBandwidth
+}
+
+// minRadioCenterFreq returns the center frequency of the radio when placing
+// the channel exactly on the left side of the available radio bandwidth.
+func (c Channel) minRadioCenterFreq() int {
+	return c.minFreq() + c.radioBandwidth()/2
+}
+
+// coveredBy returns true when the channel is covered by a radio with the
+// given center frequency.
+func (c Channel) coveredBy(radioFreq int) bool {
+	return c.minFreq() >= radioFreq-c.radioBandwidth()/2 && c.maxFreq() <= radioFreq+c.radioBandwidth()/2
+}
@@ -0,0 +1,135 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/brocaar/loraserver/api/gw"
+)
+
+func TestChannel(t *testing.T) {
+	Convey("Given a 50 kbps FSK channel of the gateway API", t, func() {
+		c := gw.Channel{
+			Modulation: gw.Modulation_FSK,
+			Frequency:  868800000,
+			Bandwidth:  125,
+			BitRate:    50000,
+		}
+
+		Convey("Then it is converted to Hz with the SX1301 bandwidth and deviation", func() {
+			channel, err := channelFromGW(&c)
+			So(err, ShouldBeNil)
+			So(channel, ShouldResemble, Channel{
+				Modulation:    FSKModulation,
+				Freq:          868800000,
+				Bandwidth:     125000,
+				BitRate:       50000,
+				FreqDeviation: 25000,
+			})
+		})
+
+		Convey("When the bandwidth is not set", func() {
+			c.Bandwidth = 0
+
+			Convey("Then the smallest SX1301 bandwidth covering the modulation is used", func() {
+				channel, err := channelFromGW(&c)
+				So(err, ShouldBeNil)
+				So(channel.Bandwidth, ShouldEqual, 125000)
+			})
+		})
+
+		Convey("Then a bandwidth too small for the bit-rate returns an error", func() {
+			c.Bandwidth = 50
+			_, err := channelFromGW(&c)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "fsk channel 868800000 Hz: bandwidth of 50000 Hz is too small for 50000 bit/s (requires 100000 Hz)")
+		})
+
+		Convey("Then a bit-rate above the SX1301 limit returns an error", func() {
+			c.Bandwidth = 0
+			c.BitRate = 300000
+			_, err := channelFromGW(&c)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Then a LoRa channel with an invalid bandwidth returns an error", t, func() {
+		_, err := channelFromGW(&gw.Channel{
+			Modulation:    gw.Modulation_LORA,
+			Frequency:     868100000,
+			Bandwidth:     62,
+			SpreadFactors: []int32{7},
+		})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given a 100 kbps FSK channel (250 kHz bandwidth)", t, func() {
+		resp := gw.GetConfigurationResponse{
+			UpdatedAt: time.Now().Format(time.RFC3339Nano),
+			Channels: []*gw.Channel{
+				{
+					Modulation: gw.Modulation_FSK,
+					Frequency:  868800000,
+					BitRate:    100000,
+				},
+			},
+		}
+
+		Convey("Then the radio is placed using the 250 kHz radio bandwidth", func() {
+			conf, err := DefaultPlanner{}.Plan(&resp)
+			So(err, ShouldBeNil)
+			So(conf.Radios[0], ShouldResemble, RadioConfig{Enable: true, Freq: 869175000})
+			So(conf.FSKChannelConfig, ShouldResemble, FSKChannelConfig{
+				Enable:        true,
+				Radio:         0,
+				IF:            -375000,
+				Bandwidth:     250000,
+				DataRate:      100000,
+				FreqDeviation: 50000,
+				Freq:          868800000,
+			})
+		})
+	})
+
+	Convey("Given a LoRa channel and a 50 kbps FSK channel at the edge of the radio", t, func() {
+		resp := gw.GetConfigurationResponse{
+			UpdatedAt: time.Now().Format(time.RFC3339Nano),
+			Channels: []*gw.Channel{
+				{
+					Modulation:    gw.Modulation_LORA,
+					Frequency:     868100000,
+					Bandwidth:     125,
+					SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+				},
+				{
+					Modulation: gw.Modulation_FSK,
+					Frequency:  868900000,
+					BitRate:    50000,
+				},
+			},
+		}
+
+		Convey("Then both channels are placed on the same radio", func() {
+			conf, err := DefaultPlanner{}.Plan(&resp)
+			So(err, ShouldBeNil)
+			So(conf.Radios[0], ShouldResemble, RadioConfig{Enable: true, Freq: 868500000})
+			So(conf.Radios[1].Enable, ShouldBeFalse)
+			So(conf.FSKChannelConfig.Radio, ShouldEqual, 0)
+			So(conf.FSKChannelConfig.IF, ShouldEqual, 400000)
+			So(conf.FSKChannelConfig.Bandwidth, ShouldEqual, 125000)
+		})
+
+		Convey("When the FSK channel exceeds the radio bandwidth", func() {
+			resp.Channels[1].Frequency = 869000000
+
+			Convey("Then the FSK channel is placed on the second radio", func() {
+				conf, err := DefaultPlanner{}.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Radios[1].Enable, ShouldBeTrue)
+				So(conf.FSKChannelConfig.Radio, ShouldEqual, 1)
+			})
+		})
+	})
+}
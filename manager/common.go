@@ -0,0 +1,20 @@
+package manager
+
+// radioBandwidthPerChannelBandwidth defines the bandwidth that a single radio
+// can cover per channel bandwidth
+var radioBandwidthPerChannelBandwidth = map[int]int{
+	500000: 1100000, // 500kHz channel
+	250000: 1000000, // 250kHz channel
+	125000: 925000,  // 125kHz channel
+}
+
+// defaultRadioBandwidth defines the radio bandwidth in case the channel
+// bandwidth does not match any of the above values (e.g. the FSK bandwidths
+// below 125kHz).
+const defaultRadioBandwidth = 925000
+
+// radioCount defines the number of radios available
+const radioCount = 2
+
+// channelCount defines the number of available channels
+const channelCount = 8
@@ -0,0 +1,301 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/brocaar/lorawan"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+var jsonCommentRegexp = regexp.MustCompile(`/\*.*\*/`)
+
+// RadioConfig contains the configuration of a radio.
+type RadioConfig struct {
+	Enable bool
+	Freq   int
+
+	// Type contains the configured radio type. When empty, the radio type
+	// of the base configuration file is kept.
+	Type string
+}
+
+// MultiSFChannelConfig contains the configuration of a LoRa multi-SF
+// channel.
+type MultiSFChannelConfig struct {
+	Enable bool
+	Radio  int
+	IF     int
+	Freq   int
+
+	// SpreadFactors contains the spread-factors of the channel, when
+	// restricted by the channel-plan. When empty, all spread-factors are
+	// enabled.
+	SpreadFactors []int
+
+	// Policy contains the regional policy of the channel (set when the
+	// band is known).
+	Policy *ChannelPolicy
+}
+
+// LoRaSTDChannelConfig contains the configuration of the LoRa (single-SF)
+// standard channel. The IF, Bandwidth and Freq are in Hz.
+type LoRaSTDChannelConfig struct {
+	Enable       bool
+	Radio        int
+	IF           int
+	Bandwidth    int
+	SpreadFactor int
+	Freq         int
+
+	// Policy contains the regional policy of the channel (set when the
+	// band is known).
+	Policy *ChannelPolicy
+}
+
+// FSKChannelConfig contains the configuration of the FSK channel. The
+// IF, Bandwidth, FreqDeviation and Freq are in Hz, the DataRate in bit/s.
+type FSKChannelConfig struct {
+	Enable        bool
+	Radio         int
+	IF            int
+	Bandwidth     int
+	DataRate      int
+	FreqDeviation int
+	Freq          int
+
+	// Policy contains the regional policy of the channel (set when the
+	// band is known).
+	Policy *ChannelPolicy
+}
+
+// GatewayConfiguration contains the planned concentrator configuration.
+type GatewayConfiguration struct {
+	UpdatedAt            time.Time
+	Radios               [radioCount]RadioConfig
+	MultiSFChannels      [channelCount]MultiSFChannelConfig
+	LoRaSTDChannelConfig LoRaSTDChannelConfig
+	FSKChannelConfig     FSKChannelConfig
+
+	// TX contains the TX configuration. When nil, the TX configuration of
+	// the base configuration file is kept.
+	TX *TXConfig
+
+	// Forwarder contains the managed gateway_conf settings. When nil, the
+	// gateway_conf of the base configuration file is kept (except for the
+	// gateway_ID).
+	Forwarder *ForwarderConfig
+
+	// Beacon contains the Class-B beacon configuration. When nil, the
+	// beacon configuration of the base configuration file is kept.
+	Beacon *BeaconConfig
+
+	// Location contains the GPS and location configuration. When nil, the
+	// GPS and location configuration of the base configuration file is
+	// kept.
+	Location *LocationConfig
+
+	// LBT contains the listen-before-talk configuration. When nil, the LBT
+	// configuration of the base configuration file is kept.
+	LBT *LBTConfig
+
+	// Rollout contains the staged rollout of the configuration, when
+	// provided by the config source (see RolloutSource).
+	Rollout *Rollout
+
+	// Warnings contains the regional policy warnings of the planned
+	// configuration (e.g. multiple channels within a sub-band with a
+	// restrictive duty-cycle).
+	Warnings []string
+}
+
+type configFile struct {
+	SX1301Conf  map[string]interface{} `json:"SX1301_conf,omitempty"`
+	SX130xConf  map[string]interface{} `json:"SX130x_conf,omitempty"`
+	GatewayConf map[string]interface{} `json:"gateway_conf"`
+}
+
+// isSX130x returns true when the configuration file is in the SX1302 HAL
+// (SX130x_conf) format. In this case, SX1301Conf and SX130xConf refer to
+// the same settings.
+func (c configFile) isSX130x() bool {
+	return c.SX130xConf != nil
+}
+
+// Writer writes the planned configuration for the packet-forwarder.
+type Writer interface {
+	Write(ctx context.Context, mac lorawan.EUI64, conf GatewayConfiguration) error
+}
+
+// FileWriter implements Writer. It loads the base configuration file,
+// injects the planned configuration and writes the result to the output
+// configuration file. Both the SX1301 (SX1301_conf) and the SX1302 HAL
+// (SX130x_conf) configuration file formats are supported.
+type FileWriter struct {
+	// BaseConfigFile contains the path to the base config file.
+	BaseConfigFile string
+
+	// OutputConfigFile contains the path to the output config file.
+	OutputConfigFile string
+}
+
+// Write writes the given configuration to the output configuration file.
+func (w FileWriter) Write(ctx context.Context, mac lorawan.EUI64, conf GatewayConfiguration) error {
+	// load base config
+	baseConf, err := loadConfigFile(w.BaseConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "load config file error")
+	}
+
+	// merge the config
+	if err = mergeConfig(mac, baseConf, conf); err != nil {
+		return errors.Wrap(err, "merge config error")
+	}
+
+	// merge the spread-factor restrictions (when supported)
+	for _, warning := range mergeSpreadFactors(baseConf, conf) {
+		log.WithField("gw_mac", mac).Warning(warning)
+	}
+
+	// write the settings under the key of the base configuration format
+	if baseConf.isSX130x() {
+		baseConf.SX1301Conf = nil
+	}
+
+	// generate config json
+	b, err := json.Marshal(baseConf)
+	if err != nil {
+		return err
+	}
+
+	// write file to disk
+	if err = ioutil.WriteFile(w.OutputConfigFile, b, 0644); err != nil {
+		return errors.Wrap(err, "write file error")
+	}
+
+	return nil
+}
+
+func loadConfigFile(filePath string) (configFile, error) {
+	var out configFile
+
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return out, errors.Wrap(err, "read file error")
+	}
+
+	// remove comments from json
+	b = jsonCommentRegexp.ReplaceAll(b, []byte{})
+
+	if err = json.Unmarshal(b, &out); err != nil {
+		return out, errors.Wrap(err, "unmarshal config json error")
+	}
+
+	// the SX1302 HAL uses the same radio and channel settings, but under
+	// the SX130x_conf key
+	if out.SX1301Conf == nil && out.SX130xConf != nil {
+		out.SX1301Conf = out.SX130xConf
+	}
+
+	return out, nil
+}
+
+// mergeConfig merges the new configuration into the given configuration.
+// Unfortunately we have to do this as the packet-forwarder sees these keys
+// as complete overrides (it does not just update the leaves).
+// We want to remain the other configuration (e.g. which radio chip is used,
+// calibration values that are board specific).
+// This is not pretty but it works.
+func mergeConfig(mac lorawan.EUI64, config configFile, newConfig GatewayConfiguration) error {
+	// update radios
+	for i, r := range newConfig.Radios {
+		radio, ok := config.SX1301Conf[fmt.Sprintf("radio_%d", i)].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected radio_%d to be of type map[string]interface{}, got %T", i, config.SX1301Conf[fmt.Sprintf("radio_%d", i)])
+		}
+		radio["enable"] = r.Enable
+		radio["freq"] = r.Freq
+		if r.Type != "" {
+			radio["type"] = r.Type
+		}
+	}
+
+	// update multi SF channels
+	for i, c := range newConfig.MultiSFChannels {
+		channel, ok := config.SX1301Conf[fmt.Sprintf("chan_multiSF_%d", i)].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected chan_multiSF_%d to be of type map[string]interface{}, got %T", i, config.SX1301Conf[fmt.Sprintf("chan_multiSF_%d", i)])
+		}
+		channel["enable"] = c.Enable
+		channel["radio"] = c.Radio
+		channel["if"] = c.IF
+	}
+
+	// update LoRa std channel
+	channel, ok := config.SX1301Conf["chan_Lora_std"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected chan_Lora_std to be of type map[string]interface{}, got %T", config.SX1301Conf["chan_Lora_std"])
+	}
+	channel["enable"] = newConfig.LoRaSTDChannelConfig.Enable
+	channel["radio"] = newConfig.LoRaSTDChannelConfig.Radio
+	channel["if"] = newConfig.LoRaSTDChannelConfig.IF
+	channel["bandwidth"] = newConfig.LoRaSTDChannelConfig.Bandwidth
+	channel["spread_factor"] = newConfig.LoRaSTDChannelConfig.SpreadFactor
+
+	// update FSK channel
+	channel, ok = config.SX1301Conf["chan_FSK"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected chan_FSK to be of type map[string]interface{}, got %T", config.SX1301Conf["chan_FSK"])
+	}
+	channel["enable"] = newConfig.FSKChannelConfig.Enable
+	channel["radio"] = newConfig.FSKChannelConfig.Radio
+	channel["if"] = newConfig.FSKChannelConfig.IF
+	channel["bandwidth"] = newConfig.FSKChannelConfig.Bandwidth
+	channel["datarate"] = newConfig.FSKChannelConfig.DataRate
+	channel["freq_deviation"] = newConfig.FSKChannelConfig.FreqDeviation
+
+	// update TX configuration
+	if newConfig.TX != nil {
+		if err := mergeTXConfig(config.SX1301Conf, *newConfig.TX); err != nil {
+			return errors.Wrap(err, "merge tx config error")
+		}
+	}
+
+	// update LBT configuration
+	if newConfig.LBT != nil {
+		if err := mergeLBTConfig(config.SX1301Conf, *newConfig.LBT); err != nil {
+			return errors.Wrap(err, "merge lbt config error")
+		}
+	}
+
+	// update packet-forwarder settings
+	if newConfig.Forwarder != nil {
+		if err := mergeForwarderConfig(config.GatewayConf, *newConfig.Forwarder); err != nil {
+			return errors.Wrap(err, "merge packet-forwarder config error")
+		}
+	}
+
+	// update Class-B beacon settings
+	if newConfig.Beacon != nil {
+		if err := mergeBeaconConfig(config.GatewayConf, *newConfig.Beacon); err != nil {
+			return errors.Wrap(err, "merge beacon config error")
+		}
+	}
+
+	// update GPS and location settings
+	if newConfig.Location != nil {
+		if err := mergeLocationConfig(config.GatewayConf, *newConfig.Location); err != nil {
+			return errors.Wrap(err, "merge location config error")
+		}
+	}
+
+	// update gateway mac / ID
+	config.GatewayConf["gateway_ID"] = mac.String()
+
+	return nil
+}
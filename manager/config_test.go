@@ -1,4 +1,4 @@
-package config
+package manager
 
 import (
 	"fmt"
@@ -12,6 +12,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 
 	"github.com/brocaar/loraserver/api/gw"
 	"github.com/brocaar/lorawan"
@@ -24,10 +25,14 @@ func init() {
 type testGatewayClient struct {
 	GetConfigurationRequestChan chan gw.GetConfigurationRequest
 	GetConfigurationResponse    gw.GetConfigurationResponse
+	GetConfigurationError       error
 }
 
 func (c *testGatewayClient) GetConfiguration(ctx context.Context, req *gw.GetConfigurationRequest, opts ...grpc.CallOption) (*gw.GetConfigurationResponse, error) {
 	c.GetConfigurationRequestChan <- *req
+	if c.GetConfigurationError != nil {
+		return nil, c.GetConfigurationError
+	}
 	return &c.GetConfigurationResponse, nil
 }
 
@@ -36,15 +41,15 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 		client := testGatewayClient{
 			GetConfigurationRequestChan: make(chan gw.GetConfigurationRequest, 100),
 		}
-		GatewayClient = &client
-		GatewayMAC = lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+		gatewayMAC := lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+		source := GatewayClientSource{Client: &client}
 		now := time.Now().UTC()
 
 		testTable := []struct {
 			Name                            string
 			GetConfigurationResponse        gw.GetConfigurationResponse
 			ExpectedGetConfigurationRequest gw.GetConfigurationRequest
-			ExpectedGatewayConfig           gatewayConfiguration
+			ExpectedGatewayConfig           GatewayConfiguration
 			ExpectedError                   error
 		}{
 			{
@@ -73,17 +78,17 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 					},
 				},
 				ExpectedGetConfigurationRequest: gw.GetConfigurationRequest{
-					Mac: GatewayMAC[:],
+					Mac: gatewayMAC[:],
 				},
-				ExpectedGatewayConfig: gatewayConfiguration{
+				ExpectedGatewayConfig: GatewayConfiguration{
 					UpdatedAt: now,
-					Radios: [radioCount]radioConfig{
+					Radios: [radioCount]RadioConfig{
 						{
 							Enable: true,
 							Freq:   868500000,
 						},
 					},
-					MultiSFChannels: [channelCount]multiSFChannelConfig{
+					MultiSFChannels: [channelCount]MultiSFChannelConfig{
 						{
 							Enable: true,
 							Radio:  0,
@@ -173,11 +178,11 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 					},
 				},
 				ExpectedGetConfigurationRequest: gw.GetConfigurationRequest{
-					Mac: GatewayMAC[:],
+					Mac: gatewayMAC[:],
 				},
-				ExpectedGatewayConfig: gatewayConfiguration{
+				ExpectedGatewayConfig: GatewayConfiguration{
 					UpdatedAt: now,
-					Radios: [radioCount]radioConfig{
+					Radios: [radioCount]RadioConfig{
 						{
 							Enable: true,
 							Freq:   867500000,
@@ -187,7 +192,7 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 							Freq:   868500000,
 						},
 					},
-					MultiSFChannels: [channelCount]multiSFChannelConfig{
+					MultiSFChannels: [channelCount]MultiSFChannelConfig{
 						{
 							Enable: true,
 							Radio:  1,
@@ -237,7 +242,7 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 							Freq:   867900000,
 						},
 					},
-					LoRaSTDChannelConfig: loRaSTDChannelConfig{
+					LoRaSTDChannelConfig: LoRaSTDChannelConfig{
 						Enable:       true,
 						Radio:        1,
 						IF:           -200000,
@@ -245,13 +250,14 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 						SpreadFactor: 7,
 						Freq:         868300000,
 					},
-					FSKChannelConfig: fskChannelConfig{
-						Enable:    true,
-						Radio:     1,
-						IF:        300000,
-						Bandwidth: 125,
-						DataRate:  50000,
-						Freq:      868800000,
+					FSKChannelConfig: FSKChannelConfig{
+						Enable:        true,
+						Radio:         1,
+						IF:            300000,
+						Bandwidth:     125000,
+						DataRate:      50000,
+						FreqDeviation: 25000,
+						Freq:          868800000,
 					},
 				},
 			},
@@ -317,11 +323,11 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 					},
 				},
 				ExpectedGetConfigurationRequest: gw.GetConfigurationRequest{
-					Mac: GatewayMAC[:],
+					Mac: gatewayMAC[:],
 				},
-				ExpectedGatewayConfig: gatewayConfiguration{
+				ExpectedGatewayConfig: GatewayConfiguration{
 					UpdatedAt: now,
-					Radios: [radioCount]radioConfig{
+					Radios: [radioCount]RadioConfig{
 						{
 							Enable: true,
 							Freq:   902700000,
@@ -331,64 +337,72 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 							Freq:   903700000,
 						},
 					},
-					MultiSFChannels: [channelCount]multiSFChannelConfig{
+					MultiSFChannels: [channelCount]MultiSFChannelConfig{
 						{
-							Enable: true,
-							Freq:   902300000,
-							Radio:  0,
-							IF:     -400000,
+							Enable:        true,
+							Freq:          902300000,
+							Radio:         0,
+							IF:            -400000,
+							SpreadFactors: []int{7, 8, 9, 10},
 						},
 						{
 
-							Enable: true,
-							Freq:   902500000,
-							Radio:  0,
-							IF:     -200000,
+							Enable:        true,
+							Freq:          902500000,
+							Radio:         0,
+							IF:            -200000,
+							SpreadFactors: []int{7, 8, 9, 10},
 						},
 						{
 
-							Enable: true,
-							Freq:   902700000,
-							Radio:  0,
-							IF:     0,
+							Enable:        true,
+							Freq:          902700000,
+							Radio:         0,
+							IF:            0,
+							SpreadFactors: []int{7, 8, 9, 10},
 						},
 						{
 
-							Enable: true,
-							Freq:   902900000,
-							Radio:  0,
-							IF:     200000,
+							Enable:        true,
+							Freq:          902900000,
+							Radio:         0,
+							IF:            200000,
+							SpreadFactors: []int{7, 8, 9, 10},
 						},
 						{
 
-							Enable: true,
-							Freq:   903100000,
-							Radio:  0,
-							IF:     400000,
+							Enable:        true,
+							Freq:          903100000,
+							Radio:         0,
+							IF:            400000,
+							SpreadFactors: []int{7, 8, 9, 10},
 						},
 						{
 
-							Enable: true,
-							Freq:   903300000,
-							Radio:  1,
-							IF:     -400000,
+							Enable:        true,
+							Freq:          903300000,
+							Radio:         1,
+							IF:            -400000,
+							SpreadFactors: []int{7, 8, 9, 10},
 						},
 						{
 
-							Enable: true,
-							Freq:   903500000,
-							Radio:  1,
-							IF:     -200000,
+							Enable:        true,
+							Freq:          903500000,
+							Radio:         1,
+							IF:            -200000,
+							SpreadFactors: []int{7, 8, 9, 10},
 						},
 						{
 
-							Enable: true,
-							Freq:   903700000,
-							Radio:  1,
-							IF:     0,
+							Enable:        true,
+							Freq:          903700000,
+							Radio:         1,
+							IF:            0,
+							SpreadFactors: []int{7, 8, 9, 10},
 						},
 					},
-					LoRaSTDChannelConfig: loRaSTDChannelConfig{
+					LoRaSTDChannelConfig: LoRaSTDChannelConfig{
 						Enable:       true,
 						Freq:         903000000,
 						Radio:        0,
@@ -406,7 +420,9 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 
 				So(client.GetConfigurationRequestChan, ShouldHaveLength, 0)
 
-				pfConfig, err := getGatewayConfig()
+				resp, err := source.GetConfiguration(context.Background(), gatewayMAC)
+				So(err, ShouldBeNil)
+				pfConfig, err := DefaultPlanner{}.Plan(resp)
 				So(err, ShouldResemble, test.ExpectedError)
 
 				So(client.GetConfigurationRequestChan, ShouldHaveLength, 1)
@@ -421,7 +437,22 @@ func TestGetPacketForwarderConfig(t *testing.T) {
 	})
 }
 
-func TestUpdateConfig(t *testing.T) {
+func TestGatewayClientSourceUnauthenticated(t *testing.T) {
+	Convey("Given a mocked GatewayClient returning an Unauthenticated error", t, func() {
+		client := testGatewayClient{
+			GetConfigurationRequestChan: make(chan gw.GetConfigurationRequest, 100),
+			GetConfigurationError:       grpc.Errorf(codes.Unauthenticated, "token is expired"),
+		}
+		source := GatewayClientSource{Client: &client}
+
+		Convey("Then GetConfiguration returns ErrUnauthenticated", func() {
+			_, err := source.GetConfiguration(context.Background(), lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8})
+			So(err, ShouldEqual, ErrUnauthenticated)
+		})
+	})
+}
+
+func TestManagerApplyOnce(t *testing.T) {
 	Convey("Given a mocked GatewayClient", t, func() {
 		now := time.Now()
 
@@ -457,22 +488,31 @@ func TestUpdateConfig(t *testing.T) {
 			},
 		}
 
-		GatewayClient = &client
-		GatewayMAC = lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
-		PFRestartCommand = fmt.Sprintf("touch %s", filepath.Join(tempDir, "restart"))
-		BaseConfigFile = filepath.Join("test/test.json")
-		OutputConfigFile = filepath.Join(tempDir, "out.json")
+		gatewayMAC := lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+		source := GatewayClientSource{Client: &client}
+		writer := FileWriter{
+			BaseConfigFile:   filepath.Join("test/test.json"),
+			OutputConfigFile: filepath.Join(tempDir, "out.json"),
+		}
+		manager, err := New(gatewayMAC,
+			WithConfigSource(source),
+			WithWriter(writer),
+			WithRestarter(CommandRestarter{Command: fmt.Sprintf("touch %s", filepath.Join(tempDir, "restart"))}),
+		)
+		So(err, ShouldBeNil)
 
-		Convey("When calling updateConfig", func() {
-			err := updateConfig()
+		Convey("When calling ApplyOnce", func() {
+			err := manager.ApplyOnce(context.Background())
 			So(err, ShouldBeNil)
 
 			Convey("Then the new configuration has been written", func() {
 				Convey("Then the new configuration contains the expected values", func() {
-					conf, err := loadConfigFile(OutputConfigFile)
+					conf, err := loadConfigFile(writer.OutputConfigFile)
 					So(err, ShouldBeNil)
 
-					gwConfig, err := getGatewayConfig()
+					resp, err := source.GetConfiguration(context.Background(), gatewayMAC)
+					So(err, ShouldBeNil)
+					gwConfig, err := DefaultPlanner{}.Plan(resp)
 					So(err, ShouldBeNil)
 
 					// test radios
@@ -516,18 +556,19 @@ func TestUpdateConfig(t *testing.T) {
 					// test FSK channel
 					channel = conf.SX1301Conf["chan_FSK"].(map[string]interface{})
 					expected = map[string]interface{}{
-						"enable":    gwConfig.FSKChannelConfig.Enable,
-						"radio":     gwConfig.FSKChannelConfig.Radio,
-						"if":        gwConfig.FSKChannelConfig.IF,
-						"bandwidth": gwConfig.FSKChannelConfig.Bandwidth,
-						"datarate":  gwConfig.FSKChannelConfig.DataRate,
+						"enable":         gwConfig.FSKChannelConfig.Enable,
+						"radio":          gwConfig.FSKChannelConfig.Radio,
+						"if":             gwConfig.FSKChannelConfig.IF,
+						"bandwidth":      gwConfig.FSKChannelConfig.Bandwidth,
+						"datarate":       gwConfig.FSKChannelConfig.DataRate,
+						"freq_deviation": gwConfig.FSKChannelConfig.FreqDeviation,
 					}
 					for k, v := range expected {
 						So(channel[k], ShouldEqual, v)
 					}
 
 					// test gateway mac / gateway_ID
-					So(conf.GatewayConf["gateway_ID"], ShouldEqual, GatewayMAC.String())
+					So(conf.GatewayConf["gateway_ID"], ShouldEqual, gatewayMAC.String())
 				})
 			})
 
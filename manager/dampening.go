@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reasons for suppressing a configuration update.
+const (
+	suppressSettle      = "settle"
+	suppressMinInterval = "min_interval"
+	suppressMaxPerHour  = "max_per_hour"
+)
+
+// DampeningConfig contains the dampening policy, protecting the
+// packet-forwarder against configuration updates flapping on the server.
+// Zero values disable the corresponding limit.
+type DampeningConfig struct {
+	// MinRestartInterval defines the minimum time between two restarts
+	// (or reloads) of the packet-forwarder.
+	MinRestartInterval time.Duration
+
+	// MaxRestartsPerHour defines the maximum number of restarts (or
+	// reloads) of the packet-forwarder within an hour.
+	MaxRestartsPerHour int
+
+	// SettlePolls defines the number of consecutive polls the planned
+	// configuration must be stable before it is applied.
+	SettlePolls int
+}
+
+// validate validates the dampening configuration.
+func (c DampeningConfig) validate() error {
+	if c.MinRestartInterval < 0 {
+		return errors.New("min. restart interval must not be negative")
+	}
+	if c.MaxRestartsPerHour < 0 {
+		return errors.New("max. restarts per hour must not be negative")
+	}
+	if c.SettlePolls < 0 {
+		return errors.New("settle polls must not be negative")
+	}
+	return nil
+}
+
+// dampener implements the dampening policy.
+type dampener struct {
+	config DampeningConfig
+
+	// restarts contains the restart timestamps within the last hour.
+	restarts []time.Time
+
+	// settleConf contains the last planned configuration and settlePolls
+	// the number of consecutive polls it has been stable.
+	settleConf  *GatewayConfiguration
+	settlePolls int
+}
+
+// observe registers the planned configuration of a poll.
+func (d *dampener) observe(conf GatewayConfiguration) {
+	if d.settleConf != nil && classifyChange(d.settleConf, conf) == ChangeNone {
+		d.settlePolls++
+	} else {
+		d.settlePolls = 1
+	}
+	d.settleConf = &conf
+}
+
+// resetSettle resets the settle state, e.g. when the server returns to the
+// applied configuration, so that a configuration flapping back must settle
+// again.
+func (d *dampener) resetSettle() {
+	d.settleConf = nil
+	d.settlePolls = 0
+}
+
+// suppress returns the reason why applying the configuration must be
+// suppressed at the given time, or an empty string when it can be applied.
+func (d *dampener) suppress(now time.Time) string {
+	if d.settlePolls < d.config.SettlePolls {
+		return suppressSettle
+	}
+
+	d.expire(now)
+	if n := len(d.restarts); n != 0 && d.config.MinRestartInterval != 0 && now.Sub(d.restarts[n-1]) < d.config.MinRestartInterval {
+		return suppressMinInterval
+	}
+	if d.config.MaxRestartsPerHour != 0 && len(d.restarts) >= d.config.MaxRestartsPerHour {
+		return suppressMaxPerHour
+	}
+
+	return ""
+}
+
+// restarted registers a restart (or reload) at the given time.
+func (d *dampener) restarted(now time.Time) {
+	d.expire(now)
+	d.restarts = append(d.restarts, now)
+}
+
+// expire removes the restarts older than an hour.
+func (d *dampener) expire(now time.Time) {
+	var i int
+	for i < len(d.restarts) && now.Sub(d.restarts[i]) >= time.Hour {
+		i++
+	}
+	d.restarts = d.restarts[i:]
+}
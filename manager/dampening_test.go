@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+func TestDampening(t *testing.T) {
+	Convey("Given a mocked GatewayClient and a flapping configuration", t, func() {
+		client := testGatewayClient{
+			GetConfigurationRequestChan: make(chan gw.GetConfigurationRequest, 100),
+		}
+		conf := GatewayConfiguration{
+			Radios: [radioCount]RadioConfig{{Enable: true, Freq: 868500000}},
+		}
+		restarter := testRestarter{}
+		clock := testClock{now: time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+		// poll advances the clock, updates the configuration on the server
+		// (when freq is set) and calls ApplyOnce.
+		poll := func(m *Manager, d time.Duration, freq int) {
+			clock.Set(clock.Now().Add(d))
+			if freq != 0 {
+				conf.Radios[0].Freq = freq
+				client.GetConfigurationResponse.UpdatedAt = clock.Now().Format(time.RFC3339Nano)
+			}
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+		}
+
+		newManager := func(c DampeningConfig) *Manager {
+			m, err := New(lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8},
+				WithConfigSource(GatewayClientSource{Client: &client}),
+				WithPlanner(testPlanner{conf: &conf}),
+				WithWriter(testWriter{}),
+				WithRestarter(&restarter),
+				WithClock(&clock),
+				WithDampening(c),
+			)
+			So(err, ShouldBeNil)
+			return m
+		}
+
+		Convey("Then an invalid dampening policy returns an error", func() {
+			_, err := New(lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8},
+				WithConfigSource(GatewayClientSource{Client: &client}),
+				WithWriter(testWriter{}),
+				WithRestarter(&restarter),
+				WithDampening(DampeningConfig{SettlePolls: -1}),
+			)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Given a minimum restart interval of 15 minutes", func() {
+			m := newManager(DampeningConfig{MinRestartInterval: 15 * time.Minute})
+			poll(m, 0, 868500000)
+			So(restarter.restarts, ShouldEqual, 1)
+
+			Convey("Then a change within the interval is suppressed", func() {
+				poll(m, 5*time.Minute, 867500000)
+				So(restarter.restarts, ShouldEqual, 1)
+
+				Convey("Then it is applied after the interval", func() {
+					poll(m, 10*time.Minute, 0)
+					So(restarter.restarts, ShouldEqual, 2)
+				})
+			})
+
+			Convey("Then a forced change within the interval is suppressed", func() {
+				clock.Set(clock.Now().Add(5 * time.Minute))
+				conf.Radios[0].Freq = 867500000
+				client.GetConfigurationResponse.UpdatedAt = clock.Now().Format(time.RFC3339Nano)
+				So(m.applyOnce(context.Background(), true), ShouldBeNil)
+				So(restarter.restarts, ShouldEqual, 1)
+			})
+
+			Convey("Then flapping back to the applied configuration does not restart", func() {
+				poll(m, 5*time.Minute, 867500000)
+				poll(m, 5*time.Minute, 868500000)
+				So(restarter.restarts, ShouldEqual, 1)
+			})
+		})
+
+		Convey("Given a maximum of 2 restarts per hour", func() {
+			m := newManager(DampeningConfig{MaxRestartsPerHour: 2})
+			poll(m, 0, 868500000)
+			poll(m, 10*time.Minute, 867500000)
+			So(restarter.restarts, ShouldEqual, 2)
+
+			Convey("Then the third change within the hour is suppressed", func() {
+				poll(m, 10*time.Minute, 868500000)
+				So(restarter.restarts, ShouldEqual, 2)
+
+				Convey("Then it is applied once the first restart is older than an hour", func() {
+					poll(m, 40*time.Minute, 0)
+					So(restarter.restarts, ShouldEqual, 3)
+				})
+			})
+		})
+
+		Convey("Given the configuration must settle for 3 polls", func() {
+			m := newManager(DampeningConfig{SettlePolls: 3})
+
+			Convey("Then a flapping configuration is not applied", func() {
+				for _, freq := range []int{868500000, 867500000, 868500000, 867500000} {
+					poll(m, 5*time.Minute, freq)
+				}
+				So(restarter.restarts, ShouldEqual, 0)
+
+				Convey("Then it is applied once stable for 3 polls", func() {
+					poll(m, 5*time.Minute, 0)
+					So(restarter.restarts, ShouldEqual, 0)
+					poll(m, 5*time.Minute, 0)
+					So(restarter.restarts, ShouldEqual, 1)
+				})
+			})
+
+			Convey("Given an applied configuration", func() {
+				poll(m, 0, 868500000)
+				poll(m, 5*time.Minute, 0)
+				poll(m, 5*time.Minute, 0)
+				So(restarter.restarts, ShouldEqual, 1)
+				applied := client.GetConfigurationResponse.UpdatedAt
+
+				Convey("Then flapping back to the applied configuration resets the settle state", func() {
+					// B for two polls, back to the applied A, B again
+					poll(m, 5*time.Minute, 867500000)
+					updated := client.GetConfigurationResponse.UpdatedAt
+					poll(m, 5*time.Minute, 0)
+					conf.Radios[0].Freq = 868500000
+					client.GetConfigurationResponse.UpdatedAt = applied
+					poll(m, 5*time.Minute, 0)
+					conf.Radios[0].Freq = 867500000
+					client.GetConfigurationResponse.UpdatedAt = updated
+					poll(m, 5*time.Minute, 0)
+					So(restarter.restarts, ShouldEqual, 1)
+
+					poll(m, 5*time.Minute, 0)
+					So(restarter.restarts, ShouldEqual, 1)
+					poll(m, 5*time.Minute, 0)
+					So(restarter.restarts, ShouldEqual, 2)
+				})
+			})
+		})
+	})
+}
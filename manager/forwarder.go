@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"fmt"
+)
+
+// ForwarderConfig contains the managed gateway_conf settings of the
+// packet-forwarder. Zero values (and nil pointers) keep the settings of the
+// base configuration file.
+type ForwarderConfig struct {
+	// ServerAddress, ServPortUp and ServPortDown define the address and
+	// ports of the packet-forwarder backend (e.g. LoRa Gateway Bridge).
+	ServerAddress string
+	ServPortUp    int
+	ServPortDown  int
+
+	// KeepaliveInterval and StatInterval define the keepalive (PULL_DATA)
+	// and statistics interval (seconds).
+	KeepaliveInterval int
+	StatInterval      int
+
+	// PushTimeoutMS defines the PUSH_DATA ack timeout (milliseconds).
+	PushTimeoutMS int
+
+	// ForwardCRCValid, ForwardCRCError and ForwardCRCDisabled define which
+	// packets are forwarded.
+	ForwardCRCValid    *bool
+	ForwardCRCError    *bool
+	ForwardCRCDisabled *bool
+}
+
+// validate validates the packet-forwarder configuration.
+func (c ForwarderConfig) validate() error {
+	for name, port := range map[string]int{"serv_port_up": c.ServPortUp, "serv_port_down": c.ServPortDown} {
+		if port < 0 || port > 65535 {
+			return fmt.Errorf("invalid %s: %d", name, port)
+		}
+	}
+	for name, v := range map[string]int{"keepalive_interval": c.KeepaliveInterval, "stat_interval": c.StatInterval, "push_timeout_ms": c.PushTimeoutMS} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative: %d", name, v)
+		}
+	}
+	return nil
+}
+
+// mergeForwarderConfig merges the packet-forwarder configuration into the
+// given gateway_conf. Settings that are not set are kept.
+func mergeForwarderConfig(gatewayConf map[string]interface{}, c ForwarderConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	if c.ServerAddress != "" {
+		gatewayConf["server_address"] = c.ServerAddress
+	}
+
+	for key, v := range map[string]int{
+		"serv_port_up":       c.ServPortUp,
+		"serv_port_down":     c.ServPortDown,
+		"keepalive_interval": c.KeepaliveInterval,
+		"stat_interval":      c.StatInterval,
+		"push_timeout_ms":    c.PushTimeoutMS,
+	} {
+		if v != 0 {
+			gatewayConf[key] = v
+		}
+	}
+
+	for key, v := range map[string]*bool{
+		"forward_crc_valid":    c.ForwardCRCValid,
+		"forward_crc_error":    c.ForwardCRCError,
+		"forward_crc_disabled": c.ForwardCRCDisabled,
+	} {
+		if v != nil {
+			gatewayConf[key] = *v
+		}
+	}
+
+	return nil
+}
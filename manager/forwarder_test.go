@@ -0,0 +1,49 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/brocaar/lorawan"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMergeForwarderConfig(t *testing.T) {
+	Convey("Given the test base configuration", t, func() {
+		conf, err := loadConfigFile("test/test.json")
+		So(err, ShouldBeNil)
+
+		Convey("When merging a packet-forwarder configuration", func() {
+			forwardCRCError := true
+			err := mergeConfig(lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}, conf, GatewayConfiguration{
+				Forwarder: &ForwarderConfig{
+					ServerAddress:     "bridge.example.com",
+					ServPortUp:        1700,
+					ServPortDown:      1701,
+					KeepaliveInterval: 20,
+					ForwardCRCError:   &forwardCRCError,
+				},
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then the set values are updated", func() {
+				So(conf.GatewayConf["server_address"], ShouldEqual, "bridge.example.com")
+				So(conf.GatewayConf["serv_port_up"], ShouldEqual, 1700)
+				So(conf.GatewayConf["serv_port_down"], ShouldEqual, 1701)
+				So(conf.GatewayConf["keepalive_interval"], ShouldEqual, 20)
+				So(conf.GatewayConf["forward_crc_error"], ShouldEqual, true)
+				So(conf.GatewayConf["gateway_ID"], ShouldEqual, "0102030405060708")
+			})
+
+			Convey("Then the other values are kept", func() {
+				So(conf.GatewayConf["stat_interval"], ShouldEqual, 30)
+				So(conf.GatewayConf["push_timeout_ms"], ShouldEqual, 100)
+				So(conf.GatewayConf["forward_crc_valid"], ShouldEqual, true)
+				So(conf.GatewayConf["forward_crc_disabled"], ShouldEqual, false)
+			})
+		})
+
+		Convey("Then an invalid port returns an error", func() {
+			So(mergeForwarderConfig(conf.GatewayConf, ForwarderConfig{ServPortUp: 70000}), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/brocaar/lorawan"
+	"github.com/pkg/errors"
+)
+
+// Gateway MAC discovery sources.
+const (
+	GatewayMACSourceConfigFile = "config-file"
+	GatewayMACSourceInterface  = "interface"
+	GatewayMACSourceCommand    = "command"
+)
+
+// DiscoverGatewayMAC discovers the gateway MAC using the given source.
+// Depending on the source, arg contains the path to the packet-forwarder
+// configuration file, the name of the network interface or the command
+// to execute.
+func DiscoverGatewayMAC(source, arg string) (lorawan.EUI64, error) {
+	switch source {
+	case GatewayMACSourceConfigFile:
+		return GatewayMACFromConfigFile(arg)
+	case GatewayMACSourceInterface:
+		return GatewayMACFromInterface(arg)
+	case GatewayMACSourceCommand:
+		return GatewayMACFromCommand(arg)
+	default:
+		return lorawan.EUI64{}, fmt.Errorf("unknown gateway mac source: %s", source)
+	}
+}
+
+// GatewayMACFromConfigFile returns the gateway MAC from the gateway_ID key
+// of the given packet-forwarder configuration file (e.g. the base or
+// local_conf.json file).
+func GatewayMACFromConfigFile(filePath string) (lorawan.EUI64, error) {
+	var mac lorawan.EUI64
+
+	conf, err := loadConfigFile(filePath)
+	if err != nil {
+		return mac, errors.Wrap(err, "load config file error")
+	}
+
+	id, ok := conf.GatewayConf["gateway_ID"].(string)
+	if !ok {
+		return mac, fmt.Errorf("expected gateway_conf.gateway_ID to be of type string, got %T", conf.GatewayConf["gateway_ID"])
+	}
+
+	if err = mac.UnmarshalText([]byte(id)); err != nil {
+		return mac, errors.Wrap(err, "unmarshal gateway_ID error")
+	}
+
+	return mac, nil
+}
+
+// GatewayMACFromInterface derives the gateway MAC from the MAC address
+// of the given network interface.
+func GatewayMACFromInterface(name string) (lorawan.EUI64, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return lorawan.EUI64{}, errors.Wrap(err, "get interface error")
+	}
+
+	return eui64FromHardwareAddr(iface.HardwareAddr)
+}
+
+// GatewayMACFromCommand executes the given (vendor specific) command and
+// parses its output as gateway MAC. Colons, dashes and surrounding
+// whitespace are ignored.
+func GatewayMACFromCommand(command string) (lorawan.EUI64, error) {
+	var mac lorawan.EUI64
+
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return mac, errors.New("no gateway mac command configured")
+	}
+
+	out, err := exec.Command(parts[0], parts[1:]...).Output()
+	if err != nil {
+		return mac, errors.Wrap(err, "execute command error")
+	}
+
+	id := strings.NewReplacer(":", "", "-", "").Replace(strings.TrimSpace(string(out)))
+	if err = mac.UnmarshalText([]byte(id)); err != nil {
+		return mac, errors.Wrap(err, "unmarshal command output error")
+	}
+
+	return mac, nil
+}
+
+// CheckGatewayMAC validates that the configured and discovered gateway MAC
+// are equal. This prevents a typo in the configured MAC from silently
+// re-identifying the gateway.
+func CheckGatewayMAC(configured, discovered lorawan.EUI64) error {
+	if configured != discovered {
+		return fmt.Errorf("configured gateway mac %s does not match discovered gateway mac %s", configured, discovered)
+	}
+
+	log.WithField("mac", discovered).Info("configured gateway mac matches discovered gateway mac")
+	return nil
+}
+
+// eui64FromHardwareAddr converts the given EUI-48 address into an EUI-64
+// by inserting FFFE in the middle (e.g. b827eb123456 > b827ebfffe123456).
+func eui64FromHardwareAddr(addr net.HardwareAddr) (lorawan.EUI64, error) {
+	var mac lorawan.EUI64
+
+	if len(addr) != 6 {
+		return mac, fmt.Errorf("expected a 6 byte hardware address, got %d bytes", len(addr))
+	}
+
+	copy(mac[0:3], addr[0:3])
+	mac[3] = 0xff
+	mac[4] = 0xfe
+	copy(mac[5:8], addr[3:6])
+
+	return mac, nil
+}
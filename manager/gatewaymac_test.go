@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/brocaar/lorawan"
+)
+
+func TestGatewayMACDiscovery(t *testing.T) {
+	Convey("Given the test base configuration file", t, func() {
+		Convey("Then GatewayMACFromConfigFile returns the gateway_ID", func() {
+			mac, err := DiscoverGatewayMAC(GatewayMACSourceConfigFile, "test/test.json")
+			So(err, ShouldBeNil)
+			So(mac, ShouldEqual, lorawan.EUI64{0xaa, 0x55, 0x5a, 0x00, 0x00, 0x00, 0x00, 0x00})
+		})
+	})
+
+	Convey("Given a command printing a MAC with separators", t, func() {
+		Convey("Then GatewayMACFromCommand returns the parsed MAC", func() {
+			mac, err := DiscoverGatewayMAC(GatewayMACSourceCommand, "echo 01:02:03:04:05:06:07:08")
+			So(err, ShouldBeNil)
+			So(mac, ShouldEqual, lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8})
+		})
+	})
+
+	Convey("Given an EUI-48 hardware address", t, func() {
+		addr := net.HardwareAddr{0xb8, 0x27, 0xeb, 0x12, 0x34, 0x56}
+
+		Convey("Then eui64FromHardwareAddr inserts FFFE", func() {
+			mac, err := eui64FromHardwareAddr(addr)
+			So(err, ShouldBeNil)
+			So(mac, ShouldEqual, lorawan.EUI64{0xb8, 0x27, 0xeb, 0xff, 0xfe, 0x12, 0x34, 0x56})
+		})
+	})
+
+	Convey("Given an unknown source", t, func() {
+		Convey("Then DiscoverGatewayMAC returns an error", func() {
+			_, err := DiscoverGatewayMAC("foo", "")
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given two different gateway MACs", t, func() {
+		Convey("Then CheckGatewayMAC returns an error", func() {
+			So(CheckGatewayMAC(lorawan.EUI64{1}, lorawan.EUI64{2}), ShouldNotBeNil)
+			So(CheckGatewayMAC(lorawan.EUI64{1}, lorawan.EUI64{1}), ShouldBeNil)
+		})
+	})
+}
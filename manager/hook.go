@@ -0,0 +1,196 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/brocaar/lorawan"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+)
+
+// defaultHookTimeout defines the default timeout of a hook.
+const defaultHookTimeout = 10 * time.Second
+
+// Event defines a configuration lifecycle event.
+type Event string
+
+// Configuration lifecycle events.
+const (
+	// EventPreApply is fired before the configuration is written.
+	EventPreApply Event = "pre-apply"
+
+	// EventPostApply is fired after the configuration has been written
+	// and the packet-forwarder has been restarted (or reloaded).
+	EventPostApply Event = "post-apply"
+
+	// EventApplyFailed is fired when the configuration could not be
+	// planned, written or the packet-forwarder could not be restarted.
+	EventApplyFailed Event = "apply-failed"
+
+	// EventRollback is fired after the previously applied configuration
+	// has been restored, because applying the new configuration failed.
+	EventRollback Event = "rollback"
+
+	// EventServerUnreachable is fired when the configuration could not be
+	// fetched from the config source.
+	EventServerUnreachable Event = "server-unreachable"
+)
+
+// HookEvent contains the event passed to the hooks.
+type HookEvent struct {
+	Event     Event         `json:"event"`
+	Time      time.Time     `json:"time"`
+	MAC       lorawan.EUI64 `json:"mac"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+
+	// Method contains the method used to activate the configuration
+	// (restart, reload or none).
+	Method string `json:"method,omitempty"`
+
+	// Radios and Channels contain the radio center frequencies and channel
+	// to radio / IF mapping of the configuration.
+	Radios   []ReportRadio   `json:"radios,omitempty"`
+	Channels []ReportChannel `json:"channels,omitempty"`
+
+	// Error contains the error (apply-failed and server-unreachable
+	// events).
+	Error string `json:"error,omitempty"`
+}
+
+// Hook handles configuration lifecycle events.
+type Hook interface {
+	Fire(ctx context.Context, e HookEvent) error
+}
+
+// HookConfig contains the configuration of a hook.
+type HookConfig struct {
+	Hook Hook
+
+	// Events defines the events passed to the hook. When empty, all
+	// events are passed.
+	Events []Event
+
+	// Timeout defines the timeout of the hook (default: 10 seconds).
+	Timeout time.Duration
+}
+
+// handles returns true when the given event must be passed to the hook.
+func (c HookConfig) handles(e Event) bool {
+	if len(c.Events) == 0 {
+		return true
+	}
+	for _, ev := range c.Events {
+		if ev == e {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandHook implements Hook by executing a command. The event is passed
+// as JSON on stdin and the event name as HOOK_EVENT environment variable.
+type CommandHook struct {
+	Command string
+}
+
+// Fire executes the command.
+func (h CommandHook) Fire(ctx context.Context, e HookEvent) error {
+	parts := strings.Fields(h.Command)
+	if len(parts) == 0 {
+		return errors.New("no hook command configured")
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "marshal json error")
+	}
+
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Env = append(os.Environ(), "HOOK_EVENT="+string(e.Event))
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "execute command error (output: %s)", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// WebhookHook implements Hook by posting the event as JSON to the
+// configured URL.
+type WebhookHook struct {
+	URL string
+
+	// Client defines the HTTP client (default: http.DefaultClient).
+	Client *http.Client
+
+	// Credentials defines the (optional) credentials used to set the
+	// Authorization header.
+	Credentials credentials.PerRPCCredentials
+}
+
+// Fire posts the event.
+func (h WebhookHook) Fire(ctx context.Context, e HookEvent) error {
+	if err := postJSON(ctx, h.Client, h.URL, h.Credentials, e); err != nil {
+		return errors.Wrap(err, "post webhook error")
+	}
+	return nil
+}
+
+// newHookEvent returns the hook event for the given configuration.
+func newHookEvent(event Event, now time.Time, mac lorawan.EUI64, conf GatewayConfiguration, method string, err error) HookEvent {
+	e := HookEvent{
+		Event:     event,
+		Time:      now,
+		MAC:       mac,
+		UpdatedAt: conf.UpdatedAt,
+		Method:    method,
+	}
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+
+	r := newReport(mac, conf, method, nil)
+	e.Radios = r.Radios
+	e.Channels = r.Channels
+	return e
+}
+
+// fireHooks passes the event to the configured hooks, sequentially and in
+// the configured order. Hook errors are logged.
+func fireHooks(ctx context.Context, logger log.FieldLogger, hooks []HookConfig, e HookEvent) {
+	for i, h := range hooks {
+		if !h.handles(e.Event) {
+			continue
+		}
+
+		timeout := h.Timeout
+		if timeout == 0 {
+			timeout = defaultHookTimeout
+		}
+
+		hookCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := h.Hook.Fire(hookCtx, e)
+		cancel()
+
+		l := logger.WithFields(log.Fields{
+			"event": e.Event,
+			"hook":  i,
+		})
+		if err != nil {
+			l.Warningf("hook error: %s", err)
+			hookErrors.WithLabelValues(string(e.Event)).Inc()
+			continue
+		}
+		l.Info("hook executed")
+	}
+}
@@ -0,0 +1,290 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+// testHook implements Hook, recording the fired events.
+type testHook struct {
+	sync.Mutex
+	events []HookEvent
+	err    error
+	delay  time.Duration
+}
+
+func (h *testHook) Fire(ctx context.Context, e HookEvent) error {
+	if h.delay != 0 {
+		select {
+		case <-time.After(h.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	h.events = append(h.events, e)
+	return h.err
+}
+
+func (h *testHook) names() []Event {
+	h.Lock()
+	defer h.Unlock()
+	var out []Event
+	for _, e := range h.events {
+		out = append(out, e.Event)
+	}
+	return out
+}
+
+// testErrRestarter implements Restarter, returning the configured errors
+// in order (nil once exhausted).
+type testErrRestarter struct {
+	restarts int
+	errs     []error
+}
+
+func (r *testErrRestarter) Restart(ctx context.Context) error {
+	r.restarts++
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+// testErrWriter implements Writer, returning err when set.
+type testErrWriter struct {
+	err error
+}
+
+func (w *testErrWriter) Write(ctx context.Context, mac lorawan.EUI64, conf GatewayConfiguration) error {
+	return w.err
+}
+
+func TestHooks(t *testing.T) {
+	Convey("Given a Manager with a hook", t, func() {
+		now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+		client := testGatewayClient{
+			GetConfigurationRequestChan: make(chan gw.GetConfigurationRequest, 100),
+			GetConfigurationResponse: gw.GetConfigurationResponse{
+				UpdatedAt: now.Format(time.RFC3339Nano),
+			},
+		}
+		conf := GatewayConfiguration{
+			Radios: [radioCount]RadioConfig{{Enable: true, Freq: 868500000}},
+		}
+		mac := lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+		hook := testHook{}
+		restarter := testErrRestarter{}
+		writer := testErrWriter{}
+
+		m, err := New(mac,
+			WithConfigSource(GatewayClientSource{Client: &client}),
+			WithPlanner(testPlanner{conf: &conf}),
+			WithWriter(&writer),
+			WithRestarter(&restarter),
+			WithHooks(HookConfig{Hook: &hook}),
+			WithClock(&testClock{now: now}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("When the configuration is applied", func() {
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+
+			Convey("Then pre-apply and post-apply are fired in order", func() {
+				So(hook.names(), ShouldResemble, []Event{EventPreApply, EventPostApply})
+				So(hook.events[1], ShouldResemble, HookEvent{
+					Event:     EventPostApply,
+					Time:      now,
+					MAC:       mac,
+					UpdatedAt: now,
+					Method:    "restart",
+					Radios: []ReportRadio{
+						{Radio: 0, Enable: true, Freq: 868500000},
+						{Radio: 1},
+					},
+				})
+			})
+
+			Convey("When the restart of the next update fails", func() {
+				hook.events = nil
+				restarter.errs = []error{errors.New("restart failed")}
+				client.GetConfigurationResponse.UpdatedAt = now.Add(time.Minute).Format(time.RFC3339Nano)
+				conf.Radios[0].Freq = 867500000
+				So(m.ApplyOnce(context.Background()), ShouldNotBeNil)
+
+				Convey("Then apply-failed and rollback are fired in order", func() {
+					So(hook.names(), ShouldResemble, []Event{EventPreApply, EventApplyFailed, EventRollback})
+					So(hook.events[1].Error, ShouldContainSubstring, "restart failed")
+				})
+
+				Convey("Then the previous configuration is restored", func() {
+					So(restarter.restarts, ShouldEqual, 3)
+					So(hook.events[2].UpdatedAt, ShouldResemble, now)
+					So(hook.events[2].Radios[0].Freq, ShouldEqual, 868500000)
+				})
+
+				Convey("Then the restarts of the rollback are taken into account by the dampening policy", func() {
+					So(m.dampener.restarts, ShouldHaveLength, 3)
+				})
+
+				Convey("Then the failed update is not retried on the next poll", func() {
+					hook.events = nil
+					So(m.ApplyOnce(context.Background()), ShouldBeNil)
+					So(restarter.restarts, ShouldEqual, 3)
+					So(hook.events, ShouldHaveLength, 0)
+
+					Convey("Then it is retried when forced", func() {
+						So(m.applyOnce(context.Background(), true), ShouldBeNil)
+						So(restarter.restarts, ShouldEqual, 4)
+						So(hook.names(), ShouldResemble, []Event{EventPreApply, EventPostApply})
+					})
+				})
+			})
+
+			Convey("When writing the next update fails", func() {
+				hook.events = nil
+				writer.err = errors.New("write failed")
+				client.GetConfigurationResponse.UpdatedAt = now.Add(time.Minute).Format(time.RFC3339Nano)
+				conf.Radios[0].Freq = 867500000
+				So(m.ApplyOnce(context.Background()), ShouldNotBeNil)
+
+				Convey("Then no rollback is fired and the packet-forwarder is not restarted", func() {
+					So(hook.names(), ShouldResemble, []Event{EventPreApply, EventApplyFailed})
+					So(hook.events[1].Error, ShouldContainSubstring, "write failed")
+					So(restarter.restarts, ShouldEqual, 1)
+				})
+
+				Convey("Then the failed update is not retried on the next poll", func() {
+					So(m.ApplyOnce(context.Background()), ShouldBeNil)
+					So(hook.events, ShouldHaveLength, 2)
+				})
+			})
+		})
+
+		Convey("When the first restart fails", func() {
+			restarter.errs = []error{errors.New("restart failed")}
+			So(m.ApplyOnce(context.Background()), ShouldNotBeNil)
+
+			Convey("Then no rollback is fired", func() {
+				So(hook.names(), ShouldResemble, []Event{EventPreApply, EventApplyFailed})
+				So(restarter.restarts, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the config source is unreachable", func() {
+			client.GetConfigurationError = errors.New("connection refused")
+			So(m.ApplyOnce(context.Background()), ShouldNotBeNil)
+
+			Convey("Then server-unreachable is fired", func() {
+				So(hook.names(), ShouldResemble, []Event{EventServerUnreachable})
+				So(hook.events[0].Error, ShouldContainSubstring, "connection refused")
+			})
+		})
+	})
+
+	Convey("Given a set of hooks", t, func() {
+		first := testHook{}
+		second := testHook{err: errors.New("hook failed")}
+		slow := testHook{delay: time.Second}
+		hooks := []HookConfig{
+			{Hook: &first, Events: []Event{EventApplyFailed}},
+			{Hook: &second},
+			{Hook: &slow, Timeout: time.Millisecond},
+		}
+		logger := log.WithField("test", true)
+
+		Convey("Then only the hooks handling the event are fired", func() {
+			fireHooks(context.Background(), logger, hooks, HookEvent{Event: EventPostApply})
+			So(first.names(), ShouldBeEmpty)
+			So(second.names(), ShouldResemble, []Event{EventPostApply})
+		})
+
+		Convey("Then a failing or timed out hook does not block the next hooks", func() {
+			fireHooks(context.Background(), logger, hooks, HookEvent{Event: EventApplyFailed})
+			So(first.names(), ShouldResemble, []Event{EventApplyFailed})
+			So(second.names(), ShouldResemble, []Event{EventApplyFailed})
+			So(slow.names(), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given a CommandHook", t, func() {
+		tempDir, err := ioutil.TempDir("", "test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+
+		script := filepath.Join(tempDir, "hook.sh")
+		So(ioutil.WriteFile(script, []byte("#!/bin/sh\necho $HOOK_EVENT > $(dirname $0)/event\ncat > $(dirname $0)/stdin\n"), 0755), ShouldBeNil)
+
+		Convey("When the hook is fired", func() {
+			h := CommandHook{Command: script}
+			So(h.Fire(context.Background(), HookEvent{Event: EventPreApply, Method: "restart"}), ShouldBeNil)
+
+			Convey("Then the event is passed as env variable and JSON on stdin", func() {
+				b, err := ioutil.ReadFile(filepath.Join(tempDir, "event"))
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, "pre-apply\n")
+
+				var e HookEvent
+				b, err = ioutil.ReadFile(filepath.Join(tempDir, "stdin"))
+				So(err, ShouldBeNil)
+				So(json.Unmarshal(b, &e), ShouldBeNil)
+				So(e.Event, ShouldEqual, EventPreApply)
+				So(e.Method, ShouldEqual, "restart")
+			})
+		})
+
+		Convey("When the command fails", func() {
+			h := CommandHook{Command: "false"}
+
+			Convey("Then an error is returned", func() {
+				So(h.Fire(context.Background(), HookEvent{Event: EventPreApply}), ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given a WebhookHook and a webhook server", t, func() {
+		var events []HookEvent
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var e HookEvent
+			if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			events = append(events, e)
+			authorization = r.Header.Get("Authorization")
+		}))
+		defer server.Close()
+
+		h := WebhookHook{URL: server.URL, Credentials: testCredentials{token: "secret"}}
+
+		Convey("When the hook is fired", func() {
+			So(h.Fire(context.Background(), HookEvent{Event: EventRollback}), ShouldBeNil)
+
+			Convey("Then the event is posted", func() {
+				So(events, ShouldHaveLength, 1)
+				So(events[0].Event, ShouldEqual, EventRollback)
+				So(authorization, ShouldEqual, "Bearer secret")
+			})
+		})
+	})
+}
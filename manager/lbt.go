@@ -0,0 +1,195 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/brocaar/lorawan"
+	"github.com/brocaar/lorawan/band"
+	"github.com/pkg/errors"
+)
+
+// lbtChannelCount defines the max. number of LBT channels supported by the
+// SX1301 HAL.
+const lbtChannelCount = 8
+
+// The SX1301 FPGA configures the LBT channels as an offset (8 bit, in steps
+// of 100 kHz) from the start frequency of the FPGA image, which is 863 MHz
+// or 915 MHz. All LBT channels must fit within the window of one of these.
+const (
+	lbtFreqStep      = 100000
+	lbtMaxFreqOffset = 255
+)
+
+var lbtStartFreqs = []int{863000000, 915000000}
+
+// bandLBT defines the default LBT RSSI target (dBm) and scan time (µs) for
+// the bands requiring LBT (Japan for AS_923, Korea for KR_920_923). When
+// required is set, LBT is mandatory for all gateways using the band.
+var bandLBT = map[band.Name]struct {
+	rssiTarget int
+	scanTime   int
+	required   bool
+}{
+	band.AS_923:     {rssiTarget: -80, scanTime: 5000},
+	band.KR_920_923: {rssiTarget: -65, scanTime: 5000, required: true},
+}
+
+// LBTConfig contains the listen-before-talk configuration of the
+// concentrator (SX1301_conf lbt_cfg).
+type LBTConfig struct {
+	// Enable enables LBT. When false, LBT is disabled.
+	Enable bool
+
+	// RSSITarget defines the RSSI (dBm) above which a channel is considered
+	// busy. When 0, the default of the band is used.
+	RSSITarget int
+
+	// ScanTime defines the scan time (µs, 128 or 5000). When 0, the default
+	// of the band is used.
+	ScanTime int
+
+	// Frequencies contains the LBT channels (Hz). These are set by the
+	// planner to all frequencies used for TX (the channels, the RX2
+	// frequency and the beacon frequency).
+	Frequencies []int
+}
+
+// validate validates the LBT configuration.
+func (c LBTConfig) validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.RSSITarget == 0 {
+		return errors.New("lbt rssi target must be set (or set the band)")
+	}
+	if c.ScanTime != 128 && c.ScanTime != 5000 {
+		return fmt.Errorf("invalid lbt scan time: %d (must be 128 or 5000)", c.ScanTime)
+	}
+	if len(c.Frequencies) > lbtChannelCount {
+		return fmt.Errorf("the %d tx frequencies %v can not be covered by the max. %d lbt channels", len(c.Frequencies), c.Frequencies, lbtChannelCount)
+	}
+	if len(c.Frequencies) != 0 && !lbtFrequenciesInWindow(c.Frequencies) {
+		return fmt.Errorf("the lbt frequencies %v do not fit the SX1301 lbt window (%d steps of %d Hz from %v Hz)", c.Frequencies, lbtMaxFreqOffset, lbtFreqStep, lbtStartFreqs)
+	}
+	return nil
+}
+
+// lbtFrequenciesInWindow returns true when all given frequencies can be
+// configured as offset from the same SX1301 LBT start frequency.
+func lbtFrequenciesInWindow(freqs []int) bool {
+	for _, start := range lbtStartFreqs {
+		ok := true
+		for _, f := range freqs {
+			offset := f - start
+			if offset < 0 || offset%lbtFreqStep != 0 || offset/lbtFreqStep > lbtMaxFreqOffset {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLBTRequired checks the LBT configuration against the LBT requirements
+// of the band. It returns an error when LBT is disabled for a band requiring
+// LBT and a warning when LBT is not managed (the base configuration might
+// enable it) or is disabled for a band requiring LBT in some countries.
+func checkLBTRequired(name band.Name, c *LBTConfig) (string, error) {
+	defaults, ok := bandLBT[name]
+	if !ok || (c != nil && c.Enable) {
+		return "", nil
+	}
+	if c == nil {
+		return fmt.Sprintf("lbt is not managed, but is required by band %s (make sure the base configuration enables lbt)", name), nil
+	}
+	if defaults.required {
+		return "", fmt.Errorf("lbt is required by band %s, but is disabled", name)
+	}
+	return fmt.Sprintf("lbt is disabled, but is required by band %s in some countries (e.g. Japan)", name), nil
+}
+
+// planLBT returns the LBT configuration for the given planned
+// configuration, covering all frequencies used for TX.
+func planLBT(c LBTConfig, name band.Name, conf GatewayConfiguration) (LBTConfig, error) {
+	if !c.Enable {
+		return c, nil
+	}
+
+	freqs := make(map[int]struct{})
+	for _, ch := range conf.MultiSFChannels {
+		if ch.Enable {
+			freqs[ch.Freq] = struct{}{}
+		}
+	}
+	if conf.LoRaSTDChannelConfig.Enable {
+		freqs[conf.LoRaSTDChannelConfig.Freq] = struct{}{}
+	}
+	if conf.FSKChannelConfig.Enable {
+		freqs[conf.FSKChannelConfig.Freq] = struct{}{}
+	}
+	if conf.Beacon != nil && conf.Beacon.Period != 0 && conf.Beacon.FreqNb <= 1 {
+		freqs[conf.Beacon.Freq] = struct{}{}
+	}
+
+	if name != "" {
+		bandConfig, err := band.GetConfig(name, false, lorawan.DwellTimeNoLimit)
+		if err != nil {
+			return c, errors.Wrap(err, "get band config error")
+		}
+		freqs[bandConfig.RX2Frequency] = struct{}{}
+
+		if defaults, ok := bandLBT[name]; ok {
+			if c.RSSITarget == 0 {
+				c.RSSITarget = defaults.rssiTarget
+			}
+			if c.ScanTime == 0 {
+				c.ScanTime = defaults.scanTime
+			}
+		}
+	}
+
+	c.Frequencies = nil
+	for f := range freqs {
+		c.Frequencies = append(c.Frequencies, f)
+	}
+	sort.Ints(c.Frequencies)
+
+	return c, c.validate()
+}
+
+// mergeLBTConfig merges the LBT configuration into the given SX1301_conf.
+// Other lbt_cfg settings of the base configuration (e.g. the board specific
+// sx127x_rssi_offset) are kept.
+func mergeLBTConfig(sx1301Conf map[string]interface{}, c LBTConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	lbt, ok := sx1301Conf["lbt_cfg"].(map[string]interface{})
+	if !ok {
+		lbt = make(map[string]interface{})
+		sx1301Conf["lbt_cfg"] = lbt
+	}
+
+	lbt["enable"] = c.Enable
+	if !c.Enable {
+		return nil
+	}
+
+	var channels []map[string]interface{}
+	for _, f := range c.Frequencies {
+		channels = append(channels, map[string]interface{}{
+			"freq_hz":      f,
+			"scan_time_us": c.ScanTime,
+		})
+	}
+	lbt["rssi_target"] = c.RSSITarget
+	lbt["nb_channel"] = len(channels)
+	lbt["chan_cfg"] = channels
+
+	return nil
+}
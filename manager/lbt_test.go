@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan/band"
+)
+
+func TestLBTConfig(t *testing.T) {
+	Convey("Given a DefaultPlanner with LBT enabled for AS_923", t, func() {
+		p := DefaultPlanner{
+			Band: band.AS_923,
+			LBT:  &LBTConfig{Enable: true},
+		}
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+		for _, f := range []int32{923200000, 923400000, 922200000, 922400000, 922600000} {
+			resp.Channels = append(resp.Channels, &gw.Channel{
+				Modulation:    gw.Modulation_LORA,
+				Frequency:     f,
+				Bandwidth:     125,
+				SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+			})
+		}
+
+		Convey("Then the LBT channels cover the channels and the RX2 frequency", func() {
+			conf, err := p.Plan(&resp)
+			So(err, ShouldBeNil)
+			So(conf.LBT, ShouldResemble, &LBTConfig{
+				Enable:      true,
+				RSSITarget:  -80,
+				ScanTime:    5000,
+				Frequencies: []int{922200000, 922400000, 922600000, 923200000, 923400000},
+			})
+		})
+
+		Convey("When more TX frequencies are used than LBT channels available", func() {
+			for _, f := range []int32{922000000, 922800000, 923000000} {
+				resp.Channels = append(resp.Channels, &gw.Channel{
+					Modulation:    gw.Modulation_LORA,
+					Frequency:     f,
+					Bandwidth:     125,
+					SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+				})
+			}
+			resp.Channels = append(resp.Channels, &gw.Channel{
+				Modulation: gw.Modulation_FSK,
+				Frequency:  923600000,
+				Bandwidth:  125,
+				BitRate:    50000,
+			})
+
+			Convey("Then an error is returned", func() {
+				_, err := p.Plan(&resp)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "lbt channels")
+			})
+		})
+
+		Convey("Then LBT without band and RSSI target returns an error", func() {
+			p.Band = ""
+			_, err := p.Plan(&resp)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("When LBT is not managed", func() {
+			p.LBT = nil
+
+			Convey("Then a warning is returned", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.LBT, ShouldBeNil)
+				So(conf.Warnings, ShouldHaveLength, 1)
+				So(conf.Warnings[0], ShouldContainSubstring, "lbt is not managed")
+			})
+		})
+
+		Convey("When LBT is disabled", func() {
+			p.LBT.Enable = false
+
+			Convey("Then a warning is returned for AS_923", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Warnings, ShouldHaveLength, 1)
+				So(conf.Warnings[0], ShouldContainSubstring, "lbt is disabled")
+			})
+
+			Convey("Then an error is returned for KR_920_923", func() {
+				p.Band = band.KR_920_923
+				_, err := checkLBTRequired(p.Band, p.LBT)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "lbt is required")
+			})
+		})
+	})
+
+	Convey("Given a set of LBT configurations", t, func() {
+		tests := []struct {
+			name        string
+			frequencies []int
+			valid       bool
+		}{
+			{"AS_923 frequencies", []int{922000000, 923200000, 923400000}, true},
+			{"EU_863_870 frequencies", []int{868100000, 869525000}, false},
+			{"frequencies not in steps of 100 kHz", []int{923250000}, false},
+			{"frequencies below the LBT window", []int{862900000}, false},
+			{"frequencies not fitting one LBT window", []int{868100000, 923200000}, false},
+			{"frequencies at the end of the LBT window", []int{915000000, 940500000}, true},
+			{"frequencies beyond the LBT window", []int{915000000, 940600000}, false},
+		}
+
+		for _, test := range tests {
+			Convey(fmt.Sprintf("Then %s are valid: %t", test.name, test.valid), func() {
+				c := LBTConfig{Enable: true, RSSITarget: -80, ScanTime: 5000, Frequencies: test.frequencies}
+				So(c.validate() == nil, ShouldEqual, test.valid)
+			})
+		}
+	})
+
+	Convey("Given a base configuration with lbt_cfg", t, func() {
+		conf, err := loadConfigFile("test/test.json")
+		So(err, ShouldBeNil)
+		conf.SX1301Conf["lbt_cfg"] = map[string]interface{}{
+			"enable":             false,
+			"sx127x_rssi_offset": float64(-4),
+		}
+
+		Convey("When merging an LBT configuration", func() {
+			So(mergeLBTConfig(conf.SX1301Conf, LBTConfig{
+				Enable:      true,
+				RSSITarget:  -80,
+				ScanTime:    5000,
+				Frequencies: []int{923200000, 923400000},
+			}), ShouldBeNil)
+
+			Convey("Then the lbt_cfg contains the LBT channels", func() {
+				lbt := conf.SX1301Conf["lbt_cfg"].(map[string]interface{})
+				So(lbt["enable"], ShouldEqual, true)
+				So(lbt["rssi_target"], ShouldEqual, -80)
+				So(lbt["nb_channel"], ShouldEqual, 2)
+				So(lbt["chan_cfg"], ShouldResemble, []map[string]interface{}{
+					{"freq_hz": 923200000, "scan_time_us": 5000},
+					{"freq_hz": 923400000, "scan_time_us": 5000},
+				})
+				So(lbt["sx127x_rssi_offset"], ShouldEqual, -4)
+			})
+		})
+	})
+}
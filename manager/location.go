@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// LocationConfig contains the GPS and location configuration of the
+// packet-forwarder (gateway_conf). Zero (nil) values keep the settings of
+// the base configuration file.
+type LocationConfig struct {
+	// GPSTTYPath defines the path to the GPS tty (e.g. /dev/ttyAMA0). When
+	// set, the tty must exist.
+	GPSTTYPath string
+
+	// DisableGPS removes the gps_tty_path, disabling the GPS.
+	DisableGPS bool
+
+	// FakeGPS enables the fake GPS, using the reference location as the
+	// gateway location. This requires the latitude and longitude to be set.
+	FakeGPS bool
+
+	// Latitude, Longitude and Altitude (meters) define the reference
+	// location of the gateway. As 0 is a valid coordinate, nil is used
+	// for not set.
+	Latitude  *float64
+	Longitude *float64
+	Altitude  *int
+}
+
+// validate validates the location configuration. When the GPS is enabled,
+// the GPS tty must exist, so that a configuration (e.g. staged for a
+// maintenance window) does not fail once it is written.
+func (c LocationConfig) validate() error {
+	if c.GPSTTYPath != "" && c.DisableGPS {
+		return errors.New("gps tty path must not be set when the gps is disabled")
+	}
+	if c.Latitude != nil && (*c.Latitude < -90 || *c.Latitude > 90) {
+		return fmt.Errorf("invalid latitude: %g", *c.Latitude)
+	}
+	if c.Longitude != nil && (*c.Longitude < -180 || *c.Longitude > 180) {
+		return fmt.Errorf("invalid longitude: %g", *c.Longitude)
+	}
+	if c.FakeGPS && (c.Latitude == nil || c.Longitude == nil) {
+		return errors.New("fake gps requires the latitude and longitude to be set")
+	}
+	if c.GPSTTYPath != "" {
+		if _, err := os.Stat(c.GPSTTYPath); err != nil {
+			return errors.Wrap(err, "gps tty error")
+		}
+	}
+	return nil
+}
+
+// mergeLocationConfig merges the location configuration into the given
+// gateway_conf.
+func mergeLocationConfig(gatewayConf map[string]interface{}, c LocationConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	if c.GPSTTYPath != "" {
+		gatewayConf["gps_tty_path"] = c.GPSTTYPath
+	}
+	if c.DisableGPS {
+		delete(gatewayConf, "gps_tty_path")
+	}
+
+	gatewayConf["fake_gps"] = c.FakeGPS
+
+	if c.Latitude != nil {
+		gatewayConf["ref_latitude"] = *c.Latitude
+	}
+	if c.Longitude != nil {
+		gatewayConf["ref_longitude"] = *c.Longitude
+	}
+	if c.Altitude != nil {
+		gatewayConf["ref_altitude"] = *c.Altitude
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/brocaar/loraserver/api/gw"
+)
+
+func TestMergeLocationConfig(t *testing.T) {
+	Convey("Given the test base configuration and a GPS tty", t, func() {
+		conf, err := loadConfigFile("test/test.json")
+		So(err, ShouldBeNil)
+
+		tempDir, err := ioutil.TempDir("", "test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+		tty := filepath.Join(tempDir, "ttyAMA0")
+		So(ioutil.WriteFile(tty, nil, 0600), ShouldBeNil)
+
+		Convey("When merging a location configuration with GPS", func() {
+			lat, lon, alt := 52.3676, 4.9041, 5
+			err := mergeLocationConfig(conf.GatewayConf, LocationConfig{
+				GPSTTYPath: tty,
+				Latitude:   &lat,
+				Longitude:  &lon,
+				Altitude:   &alt,
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then the GPS and location settings are set", func() {
+				So(conf.GatewayConf["gps_tty_path"], ShouldEqual, tty)
+				So(conf.GatewayConf["fake_gps"], ShouldEqual, false)
+				So(conf.GatewayConf["ref_latitude"], ShouldEqual, 52.3676)
+				So(conf.GatewayConf["ref_longitude"], ShouldEqual, 4.9041)
+				So(conf.GatewayConf["ref_altitude"], ShouldEqual, 5)
+			})
+
+			Convey("When merging a location configuration with the GPS disabled", func() {
+				So(mergeLocationConfig(conf.GatewayConf, LocationConfig{DisableGPS: true}), ShouldBeNil)
+
+				Convey("Then the gps_tty_path has been removed and the location is kept", func() {
+					_, ok := conf.GatewayConf["gps_tty_path"]
+					So(ok, ShouldBeFalse)
+					So(conf.GatewayConf["ref_latitude"], ShouldEqual, 52.3676)
+				})
+			})
+		})
+
+		Convey("Then a missing GPS tty returns an error", func() {
+			err := mergeLocationConfig(conf.GatewayConf, LocationConfig{GPSTTYPath: filepath.Join(tempDir, "ttyS0")})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Then fake GPS without location returns an error", func() {
+			So(mergeLocationConfig(conf.GatewayConf, LocationConfig{FakeGPS: true}), ShouldNotBeNil)
+		})
+
+		Convey("Then an invalid latitude returns an error", func() {
+			lat, lon := 91.0, 4.9
+			So(mergeLocationConfig(conf.GatewayConf, LocationConfig{Latitude: &lat, Longitude: &lon}), ShouldNotBeNil)
+		})
+
+		Convey("When merging a fake GPS location at latitude and longitude 0", func() {
+			var lat, lon float64
+			err := mergeLocationConfig(conf.GatewayConf, LocationConfig{FakeGPS: true, Latitude: &lat, Longitude: &lon})
+			So(err, ShouldBeNil)
+
+			Convey("Then the location is set", func() {
+				So(conf.GatewayConf["fake_gps"], ShouldEqual, true)
+				So(conf.GatewayConf["ref_latitude"], ShouldEqual, 0)
+				So(conf.GatewayConf["ref_longitude"], ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given a DefaultPlanner with a missing GPS tty", t, func() {
+		p := DefaultPlanner{Location: &LocationConfig{GPSTTYPath: "/dev/does-not-exist"}}
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+
+		Convey("Then planning returns an error", func() {
+			_, err := p.Plan(&resp)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "gps tty error")
+		})
+	})
+}
@@ -0,0 +1,519 @@
+// Package manager implements the channel-configuration management of a
+// gateway. It fetches the channel-configuration from a ConfigSource, plans
+// the concentrator configuration, writes it for the packet-forwarder and
+// restarts the packet-forwarder.
+//
+// This package can be embedded by other (gateway) applications:
+//
+//	m, err := manager.New(mac,
+//		manager.WithConfigSource(manager.GatewayClientSource{Client: client}),
+//		manager.WithWriter(manager.FileWriter{
+//			BaseConfigFile:   "/etc/lora-pkt-fwd/global_conf.json",
+//			OutputConfigFile: "/etc/lora-pkt-fwd/local_conf.json",
+//		}),
+//		manager.WithRestarter(manager.CommandRestarter{Command: "systemctl restart lora-pkt-fwd"}),
+//	)
+//	if err != nil {
+//		// handle error
+//	}
+//	go m.Run(ctx)
+package manager
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// defaultPollInterval defines the default interval between polling new
+// configuration.
+const defaultPollInterval = 5 * time.Minute
+
+// Clock provides the current time and timers. It can be replaced for
+// testing.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Option configures the Manager.
+type Option func(*Manager)
+
+// WithConfigSource sets the source of the channel-configuration (required).
+func WithConfigSource(s ConfigSource) Option {
+	return func(m *Manager) {
+		m.source = s
+	}
+}
+
+// WithPlanner sets the planner (default: DefaultPlanner).
+func WithPlanner(p Planner) Option {
+	return func(m *Manager) {
+		m.planner = p
+	}
+}
+
+// WithWriter sets the writer of the planned configuration (required).
+func WithWriter(w Writer) Option {
+	return func(m *Manager) {
+		m.writer = w
+	}
+}
+
+// WithRestarter sets the packet-forwarder restarter (required).
+func WithRestarter(r Restarter) Option {
+	return func(m *Manager) {
+		m.restarter = r
+	}
+}
+
+// WithReloader sets the packet-forwarder reloader (optional). When set,
+// changes that only affect the gateway_conf settings are applied by
+// reloading the packet-forwarder instead of a full restart.
+func WithReloader(r Reloader) Option {
+	return func(m *Manager) {
+		m.reloader = r
+	}
+}
+
+// WithMaintenanceWindows sets the maintenance windows (optional). When
+// set, configuration updates are only applied within one of the windows.
+// Outside the windows, updates are planned (validated) and staged until the
+// next window, or until ApplyNow is called.
+func WithMaintenanceWindows(windows ...MaintenanceWindow) Option {
+	return func(m *Manager) {
+		m.windows = windows
+	}
+}
+
+// WithDampening sets the dampening policy (optional), limiting the number
+// of packet-forwarder restarts when the configuration on the server is
+// flapping.
+func WithDampening(c DampeningConfig) Option {
+	return func(m *Manager) {
+		m.dampener.config = c
+	}
+}
+
+// WithReporter sets the reporter (optional), reporting the outcome of each
+// configuration update back to the server.
+func WithReporter(r Reporter) Option {
+	return func(m *Manager) {
+		m.reporter = r
+	}
+}
+
+// WithHooks sets the hooks (optional), which are fired on the
+// configuration lifecycle events.
+func WithHooks(hooks ...HookConfig) Option {
+	return func(m *Manager) {
+		m.hooks = hooks
+	}
+}
+
+// WithClock sets the clock (default: the system clock).
+func WithClock(c Clock) Option {
+	return func(m *Manager) {
+		m.clock = c
+	}
+}
+
+// WithLogger sets the logger (default: the standard logrus logger with
+// the gw_mac field).
+func WithLogger(l log.FieldLogger) Option {
+	return func(m *Manager) {
+		m.log = l
+	}
+}
+
+// WithPollInterval sets the interval between polling new configuration
+// (default: 5 minutes).
+func WithPollInterval(d time.Duration) Option {
+	return func(m *Manager) {
+		m.pollInterval = d
+	}
+}
+
+// Manager manages the channel-configuration of a single gateway.
+type Manager struct {
+	mac           lorawan.EUI64
+	source        ConfigSource
+	planner       Planner
+	writer        Writer
+	restarter     Restarter
+	reloader      Reloader
+	reporter      Reporter
+	hooks         []HookConfig
+	clock         Clock
+	log           log.FieldLogger
+	pollInterval  time.Duration
+	windows       []MaintenanceWindow
+	rolloutBucket int
+	dampener      dampener
+	applyNow      chan struct{}
+	lastUpdatedAt time.Time
+	lastApplied   *GatewayConfiguration
+	lastFailedAt  time.Time
+
+	mu       sync.Mutex
+	lastPlan *GatewayConfiguration
+	staged   *GatewayConfiguration
+}
+
+// New creates a new Manager for the given gateway MAC.
+func New(mac lorawan.EUI64, opts ...Option) (*Manager, error) {
+	m := Manager{
+		mac:           mac,
+		planner:       DefaultPlanner{},
+		clock:         realClock{},
+		log:           log.WithField("gw_mac", mac),
+		pollInterval:  defaultPollInterval,
+		applyNow:      make(chan struct{}, 1),
+		rolloutBucket: RolloutBucket(mac),
+	}
+
+	for _, o := range opts {
+		o(&m)
+	}
+
+	if m.source == nil {
+		return nil, errors.New("config source must be set")
+	}
+	if m.writer == nil {
+		return nil, errors.New("writer must be set")
+	}
+	if m.restarter == nil {
+		return nil, errors.New("restarter must be set")
+	}
+	if err := m.dampener.config.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid dampening config")
+	}
+
+	return &m, nil
+}
+
+// Run checks for new configuration, writes new configuration and restarts
+// the packet-forwarder every poll interval, until the given context is
+// cancelled.
+func (m *Manager) Run(ctx context.Context) error {
+	var force bool
+	for {
+		m.log.Info("checking for updated configuration")
+		if err := m.applyOnce(ctx, force); err != nil {
+			configUpdateErrors.WithLabelValues(m.mac.String()).Inc()
+			m.log.Errorf("update config error: %s", err)
+		}
+		m.log.WithField("duration", m.pollInterval).Info("sleeping until next update check")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.clock.After(m.pollInterval):
+			force = false
+		case <-m.applyNow:
+			m.log.Info("apply now requested")
+			force = true
+		}
+	}
+}
+
+// ApplyNow requests Run to fetch and apply the latest configuration
+// immediately, also when outside the maintenance windows (e.g. for urgent
+// changes). The update is still subject to the rollout and dampening
+// policies.
+func (m *Manager) ApplyNow() {
+	select {
+	case m.applyNow <- struct{}{}:
+	default:
+	}
+}
+
+// Plan fetches the latest configuration from the config source and returns
+// the planned concentrator configuration, without writing it.
+func (m *Manager) Plan(ctx context.Context) (GatewayConfiguration, error) {
+	resp, rollout, err := m.getConfiguration(ctx)
+	if err != nil {
+		return GatewayConfiguration{}, err
+	}
+	return m.plan(resp, rollout)
+}
+
+// getConfiguration fetches the latest configuration and its rollout from
+// the config source.
+func (m *Manager) getConfiguration(ctx context.Context) (*gw.GetConfigurationResponse, *Rollout, error) {
+	var resp *gw.GetConfigurationResponse
+	var rollout *Rollout
+	var err error
+	if rs, ok := m.source.(RolloutSource); ok {
+		resp, rollout, err = rs.GetConfigurationRollout(ctx, m.mac)
+	} else {
+		resp, err = m.source.GetConfiguration(ctx, m.mac)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "get packet-forwarder config error")
+	}
+	return resp, rollout, nil
+}
+
+// plan plans the given configuration.
+func (m *Manager) plan(resp *gw.GetConfigurationResponse, rollout *Rollout) (GatewayConfiguration, error) {
+	conf, err := m.planner.Plan(resp)
+	if err != nil {
+		return conf, errors.Wrap(err, "plan packet-forwarder config error")
+	}
+	conf.Rollout = rollout
+
+	m.mu.Lock()
+	m.lastPlan = &conf
+	m.mu.Unlock()
+
+	return conf, nil
+}
+
+// LastPlan returns the last planned configuration, or nil when no
+// configuration has been planned yet.
+func (m *Manager) LastPlan() *GatewayConfiguration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastPlan
+}
+
+// Staged returns the configuration staged until the next maintenance
+// window, or nil when no configuration is staged.
+func (m *Manager) Staged() *GatewayConfiguration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.staged
+}
+
+// MAC returns the gateway MAC of the Manager.
+func (m *Manager) MAC() lorawan.EUI64 {
+	return m.mac
+}
+
+// ApplyOnce fetches the latest configuration from the config source and
+// when updated, plans the concentrator configuration, writes it and
+// restarts or reloads the packet-forwarder, depending on the kind of
+// change. When maintenance windows are configured and none of these is
+// open, the update is staged instead.
+func (m *Manager) ApplyOnce(ctx context.Context) error {
+	return m.applyOnce(ctx, false)
+}
+
+// applyOnce implements ApplyOnce. When force is set, the update is applied
+// regardless the maintenance windows and an update which failed to apply
+// before is retried. The rollout and dampening policies still apply.
+func (m *Manager) applyOnce(ctx context.Context, force bool) error {
+	resp, rollout, err := m.getConfiguration(ctx)
+	if err != nil {
+		m.fire(ctx, EventServerUnreachable, GatewayConfiguration{}, "", err)
+		m.report(ctx, GatewayConfiguration{}, "", err)
+		return err
+	}
+
+	conf, err := m.plan(resp, rollout)
+	if err != nil {
+		m.fire(ctx, EventApplyFailed, conf, "", err)
+		m.report(ctx, conf, "", err)
+		return err
+	}
+
+	if m.lastUpdatedAt.Equal(conf.UpdatedAt) {
+		m.log.Info("no configuration update available")
+		m.setStaged(nil)
+		m.dampener.resetSettle()
+		configRolloutPending.WithLabelValues(m.mac.String()).Set(0)
+		return nil
+	}
+
+	// do not retry (and restart the packet-forwarder) on every poll when
+	// applying this update failed before
+	if !force && m.lastFailedAt.Equal(conf.UpdatedAt) {
+		m.log.WithField("updated_at", conf.UpdatedAt).Warning("applying this configuration update failed before, waiting for a new configuration update")
+		m.setStaged(nil)
+		return nil
+	}
+
+	for _, w := range conf.Warnings {
+		m.log.Warningf("regional policy warning: %s", w)
+	}
+
+	if r := conf.Rollout; r != nil && !r.admits(m.rolloutBucket, m.clock.Now()) {
+		m.log.WithFields(log.Fields{
+			"updated_at":         conf.UpdatedAt,
+			"rollout_percentage": r.Percentage,
+			"rollout_deadline":   r.Deadline,
+			"rollout_bucket":     m.rolloutBucket,
+		}).Info("configuration update not yet admitted by rollout, keeping previous configuration")
+		configRolloutPending.WithLabelValues(m.mac.String()).Set(1)
+		return nil
+	}
+	configRolloutPending.WithLabelValues(m.mac.String()).Set(0)
+
+	m.dampener.observe(conf)
+	kind := classifyChange(m.lastApplied, conf)
+
+	if !force && !m.inMaintenanceWindow() {
+		m.log.WithField("updated_at", conf.UpdatedAt).Info("outside maintenance window, configuration update staged")
+		m.setStaged(&conf)
+		return nil
+	}
+
+	if reason := m.dampener.suppress(m.clock.Now()); kind != ChangeNone && reason != "" {
+		m.log.WithFields(log.Fields{
+			"updated_at":   conf.UpdatedAt,
+			"reason":       reason,
+			"settle_polls": m.dampener.settlePolls,
+		}).Warning("configuration update suppressed by dampening policy")
+		configUpdatesSuppressed.WithLabelValues(m.mac.String(), reason).Inc()
+		return nil
+	}
+
+	m.fire(ctx, EventPreApply, conf, "", nil)
+
+	// write the configuration, the packet-forwarder has not been restarted
+	// yet so a rollback is not needed when this fails
+	if err = m.writer.Write(ctx, m.mac, conf); err != nil {
+		err = errors.Wrap(err, "write config error")
+		m.lastFailedAt = conf.UpdatedAt
+		m.fire(ctx, EventApplyFailed, conf, "", err)
+		m.report(ctx, conf, "", err)
+		return err
+	}
+	m.log.Info("configuration written")
+	configUpdates.WithLabelValues(m.mac.String()).Inc()
+
+	// restart or reload the packet-forwarder
+	method, err := m.applyChange(ctx, kind)
+	if kind != ChangeNone {
+		m.dampener.restarted(m.clock.Now())
+	}
+	if err != nil {
+		m.lastFailedAt = conf.UpdatedAt
+		m.fire(ctx, EventApplyFailed, conf, method, err)
+		m.report(ctx, conf, method, err)
+		m.rollback(ctx)
+		return err
+	}
+
+	// set last updated timestamp
+	m.lastUpdatedAt = conf.UpdatedAt
+	m.lastApplied = &conf
+	m.setStaged(nil)
+	configUpdatedAt.WithLabelValues(m.mac.String()).Set(float64(conf.UpdatedAt.Unix()))
+	m.fire(ctx, EventPostApply, conf, method, nil)
+	m.report(ctx, conf, method, nil)
+
+	return nil
+}
+
+// rollback restores the previously applied configuration after restarting
+// (or reloading) the packet-forwarder with a new configuration failed. When
+// no configuration has been applied yet, this is a no-op.
+func (m *Manager) rollback(ctx context.Context) {
+	if m.lastApplied == nil {
+		return
+	}
+	conf := *m.lastApplied
+
+	if err := m.writer.Write(ctx, m.mac, conf); err != nil {
+		m.log.Errorf("rollback: write config error: %s", err)
+		return
+	}
+	err := m.restarter.Restart(ctx)
+	m.dampener.restarted(m.clock.Now())
+	if err != nil {
+		m.log.Errorf("rollback: invoke packet-forwarder restart error: %s", err)
+		return
+	}
+
+	m.log.WithField("updated_at", conf.UpdatedAt).Warning("rolled back to previously applied configuration")
+	m.fire(ctx, EventRollback, conf, "restart", nil)
+}
+
+// fire fires the given event to the configured hooks.
+func (m *Manager) fire(ctx context.Context, event Event, conf GatewayConfiguration, method string, err error) {
+	if len(m.hooks) == 0 {
+		return
+	}
+	fireHooks(ctx, m.log, m.hooks, newHookEvent(event, m.clock.Now(), m.mac, conf, method, err))
+}
+
+// report reports the outcome of the configuration update, when a reporter
+// is configured. Report errors are logged.
+func (m *Manager) report(ctx context.Context, conf GatewayConfiguration, method string, err error) {
+	if m.reporter == nil {
+		return
+	}
+	if err := m.reporter.Report(ctx, newReport(m.mac, conf, method, err)); err != nil {
+		m.log.Warningf("report configuration update error: %s", err)
+	}
+}
+
+// inMaintenanceWindow returns true when no maintenance windows are
+// configured or when one of the windows is open.
+func (m *Manager) inMaintenanceWindow() bool {
+	if len(m.windows) == 0 {
+		return true
+	}
+	now := m.clock.Now()
+	for _, w := range m.windows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// setStaged sets the staged configuration and updates the staged metrics.
+func (m *Manager) setStaged(conf *GatewayConfiguration) {
+	m.mu.Lock()
+	m.staged = conf
+	m.mu.Unlock()
+
+	if conf == nil {
+		configStaged.WithLabelValues(m.mac.String()).Set(0)
+		return
+	}
+	configStaged.WithLabelValues(m.mac.String()).Set(1)
+	configStagedUpdatedAt.WithLabelValues(m.mac.String()).Set(float64(conf.UpdatedAt.Unix()))
+}
+
+// applyChange restarts or reloads the packet-forwarder for the given kind
+// of change. When the reload fails, it falls back to a full restart. It
+// returns the used method (restart, reload or none).
+func (m *Manager) applyChange(ctx context.Context, kind ChangeKind) (string, error) {
+	logger := m.log.WithField("change", kind)
+
+	switch {
+	case kind == ChangeNone:
+		logger.Info("configuration unchanged, skipping packet-forwarder restart")
+		pfReloads.WithLabelValues(m.mac.String(), "none").Inc()
+		return "none", nil
+	case kind == ChangeGatewayConf && m.reloader != nil:
+		err := m.reloader.Reload(ctx)
+		if err == nil {
+			logger.Info("packet-forwarder reloaded")
+			pfReloads.WithLabelValues(m.mac.String(), "reload").Inc()
+			return "reload", nil
+		}
+		logger.Warningf("reload packet-forwarder error, falling back on restart: %s", err)
+	}
+
+	if err := m.restarter.Restart(ctx); err != nil {
+		return "restart", errors.Wrap(err, "invoke packet-forwarder restart error")
+	}
+	logger.Info("packet-forwarder restarted")
+	pfReloads.WithLabelValues(m.mac.String(), "restart").Inc()
+	return "restart", nil
+}
@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+// testClock implements a Clock which timers fire when Advance is called.
+// As the manager reads the clock from its Run goroutine, now is protected
+// by the mutex.
+type testClock struct {
+	sync.Mutex
+	now    time.Time
+	afterC chan time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time { return c.afterC }
+
+// Set sets the current time of the clock.
+func (c *testClock) Set(now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+	c.afterC <- c.Now()
+}
+
+func TestManager(t *testing.T) {
+	Convey("Given a mocked GatewayClient", t, func() {
+		client := testGatewayClient{
+			GetConfigurationRequestChan: make(chan gw.GetConfigurationRequest, 100),
+			GetConfigurationResponse: gw.GetConfigurationResponse{
+				UpdatedAt: time.Now().Format(time.RFC3339Nano),
+			},
+		}
+		source := GatewayClientSource{Client: &client}
+
+		Convey("Then New returns an error when a required option is missing", func() {
+			_, err := New(lorawan.EUI64{}, WithWriter(FileWriter{}), WithRestarter(CommandRestarter{}))
+			So(err, ShouldNotBeNil)
+			_, err = New(lorawan.EUI64{}, WithConfigSource(source), WithRestarter(CommandRestarter{}))
+			So(err, ShouldNotBeNil)
+			_, err = New(lorawan.EUI64{}, WithConfigSource(source), WithWriter(FileWriter{}))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Given a Manager with a test clock", func() {
+			clock := testClock{now: time.Now(), afterC: make(chan time.Time)}
+			m, err := New(lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8},
+				WithConfigSource(source),
+				WithWriter(FileWriter{}),
+				WithRestarter(CommandRestarter{}),
+				WithClock(&clock),
+				WithPollInterval(time.Minute),
+			)
+			So(err, ShouldBeNil)
+
+			Convey("Then Run polls every interval until the context is cancelled", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan error)
+				go func() {
+					done <- m.Run(ctx)
+				}()
+
+				<-client.GetConfigurationRequestChan
+				clock.Advance(time.Minute)
+				<-client.GetConfigurationRequestChan
+
+				cancel()
+				So(<-done, ShouldEqual, context.Canceled)
+			})
+		})
+	})
+}
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	configUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lora_channel_manager_config_updates_total",
+		Help: "Number of configuration updates written to disk (per gateway).",
+	}, []string{"gw_mac"})
+
+	configUpdateErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lora_channel_manager_config_update_errors_total",
+		Help: "Number of failed configuration update checks (per gateway).",
+	}, []string{"gw_mac"})
+
+	configUpdatedAt = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lora_channel_manager_config_updated_at_timestamp_seconds",
+		Help: "UpdatedAt timestamp of the configuration applied last (per gateway), as unix timestamp.",
+	}, []string{"gw_mac"})
+
+	configStaged = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lora_channel_manager_config_staged",
+		Help: "Set to 1 when a configuration update is staged until the next maintenance window (per gateway).",
+	}, []string{"gw_mac"})
+
+	configStagedUpdatedAt = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lora_channel_manager_config_staged_updated_at_timestamp_seconds",
+		Help: "UpdatedAt timestamp of the staged configuration (per gateway), as unix timestamp.",
+	}, []string{"gw_mac"})
+
+	configUpdatesSuppressed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lora_channel_manager_config_updates_suppressed_total",
+		Help: "Number of configuration updates suppressed by the dampening policy (per gateway and reason: settle, min_interval or max_per_hour).",
+	}, []string{"gw_mac", "reason"})
+
+	configRolloutPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lora_channel_manager_config_rollout_pending",
+		Help: "Set to 1 when a configuration update is pending because the gateway is not yet admitted by the rollout (per gateway).",
+	}, []string{"gw_mac"})
+
+	hookErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lora_channel_manager_hook_errors_total",
+		Help: "Number of failed hook executions (per event).",
+	}, []string{"event"})
+
+	pfReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lora_channel_manager_pf_reloads_total",
+		Help: "Number of applied configuration updates (per gateway and method: restart, reload or none).",
+	}, []string{"gw_mac", "method"})
+)
+
+func init() {
+	prometheus.MustRegister(configUpdates)
+	prometheus.MustRegister(configUpdateErrors)
+	prometheus.MustRegister(configUpdatedAt)
+	prometheus.MustRegister(pfReloads)
+	prometheus.MustRegister(configStaged)
+	prometheus.MustRegister(configStagedUpdatedAt)
+	prometheus.MustRegister(configUpdatesSuppressed)
+	prometheus.MustRegister(configRolloutPending)
+	prometheus.MustRegister(hookErrors)
+}
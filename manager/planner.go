@@ -0,0 +1,349 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+	"github.com/brocaar/lorawan/band"
+	"github.com/pkg/errors"
+)
+
+// Planner plans the concentrator configuration (radios and channels) for the
+// channel-configuration given by the ConfigSource.
+type Planner interface {
+	Plan(resp *gw.GetConfigurationResponse) (GatewayConfiguration, error)
+}
+
+// DefaultPlanner implements the Planner for the SX1301 concentrator.
+type DefaultPlanner struct {
+	// TX contains the TX configuration (optional). As the gateway-server
+	// does not provide the TX configuration, it must be configured locally.
+	TX *TXConfig
+
+	// Band defines the LoRaWAN band of the gateway (optional). When set,
+	// the max. EIRP of the band is used when the TX configuration does not
+	// define a max. EIRP and the planned channels are annotated with the
+	// regional policy of the band.
+	Band band.Name
+
+	// Forwarder contains the managed gateway_conf settings (optional). As
+	// the gateway-server does not provide these settings, they must be
+	// configured locally.
+	Forwarder *ForwarderConfig
+
+	// Beacon contains the Class-B beacon configuration (optional). When the
+	// beacon is enabled, the values that are not set default to the beacon
+	// settings of the band.
+	Beacon *BeaconConfig
+
+	// Location contains the GPS and location configuration (optional). As
+	// the gateway-server does not provide the gateway location, it must be
+	// configured locally.
+	Location *LocationConfig
+
+	// LBT contains the listen-before-talk configuration (optional). When
+	// enabled, the LBT channels are planned to cover all TX frequencies.
+	// For the bands requiring LBT, a warning is added to the planned
+	// configuration when LBT is not enabled (KR_920_923 fails when LBT is
+	// disabled).
+	LBT *LBTConfig
+
+	// DwellTime defines if the dwell time limitation applies (AS_923 only,
+	// e.g. in Japan). Together with the Band, it is used for the regional
+	// policy evaluation of the planned channels.
+	DwellTime lorawan.DwellTime
+
+	// Radios contains the radio hardware configuration (optional). The
+	// values that are set override the radio types of the base
+	// configuration file. The radios are only placed within their allowed
+	// frequency range.
+	Radios [radioCount]RadioHardwareConfig
+
+	// BaseConfigFile defines the base configuration file (optional). When
+	// set, the radio types (radio_N.type), the TX radio (radio_N.tx_enable)
+	// and the radio providing the clock (clksrc) are read from this file.
+	// The planner makes sure that the TX radio and the clock source are
+	// enabled and that the downlink frequencies are reachable by the TX
+	// radio.
+	BaseConfigFile string
+
+	// PreviousConfigFile defines the previously written configuration file
+	// (optional, e.g. the output configuration file). When set, the
+	// multi-SF channels are kept in the same channel slot (chan_multiSF_N)
+	// as in this file, so that incremental channel-plan changes result in
+	// minimal configuration changes.
+	PreviousConfigFile string
+}
+
+// radioHardware returns the radio hardware configuration and the radio
+// providing the clock (-1 when unknown), based on the base configuration
+// file and the configured radios.
+func (p DefaultPlanner) radioHardware() ([radioCount]RadioHardwareConfig, int, error) {
+	var hw [radioCount]RadioHardwareConfig
+	clkSrc := -1
+
+	if p.BaseConfigFile != "" {
+		baseConf, err := loadConfigFile(p.BaseConfigFile)
+		if err != nil {
+			return hw, clkSrc, errors.Wrap(err, "load config file error")
+		}
+		if hw, err = getRadioHardware(baseConf.SX1301Conf); err != nil {
+			return hw, clkSrc, err
+		}
+		if clkSrc, err = getClockSource(baseConf.SX1301Conf); err != nil {
+			return hw, clkSrc, errors.Wrap(err, "get clock source error")
+		}
+	}
+
+	for i, r := range p.Radios {
+		if err := r.validate(); err != nil {
+			return hw, clkSrc, errors.Wrapf(err, "radio_%d config error", i)
+		}
+		if r.Type != "" {
+			hw[i].Type = r.Type
+		}
+		if r.FreqMin != 0 || r.FreqMax != 0 {
+			hw[i].FreqMin = r.FreqMin
+			hw[i].FreqMax = r.FreqMax
+		}
+	}
+
+	return hw, clkSrc, nil
+}
+
+// channelByMinRadioCenterFreqency implements sort.Interface for []Channel.
+// The sorting is based on the center frequency of the radio when placing the
+// channel exactly on the left side of the available radio bandwidth.
+type channelByMinRadioCenterFrequency []Channel
+
+func (c channelByMinRadioCenterFrequency) Len() int      { return len(c) }
+func (c channelByMinRadioCenterFrequency) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c channelByMinRadioCenterFrequency) Less(i, j int) bool {
+	return c[i].minRadioCenterFreq() < c[j].minRadioCenterFreq()
+}
+
+// Plan plans the radios and channels for the given configuration.
+func (p DefaultPlanner) Plan(configResp *gw.GetConfigurationResponse) (GatewayConfiguration, error) {
+	var conf GatewayConfiguration
+	var multiSFChannels []MultiSFChannelConfig
+
+	// set UpdatedAt
+	ts, err := time.Parse(time.RFC3339Nano, configResp.UpdatedAt)
+	if err != nil {
+		return conf, errors.Wrap(err, "parse time error")
+	}
+	conf.UpdatedAt = ts
+
+	// set TX configuration
+	if p.TX != nil {
+		tx := *p.TX
+		if tx.MaxEIRP == 0 && p.Band != "" {
+			var freqMin, freqMax int
+			if !tx.KeepRadios {
+				freqMin, freqMax = tx.FreqMin, tx.FreqMax
+			}
+			if tx.MaxEIRP, err = getBandMaxEIRP(p.Band, freqMin, freqMax); err != nil {
+				return conf, errors.Wrap(err, "get band max eirp error")
+			}
+		}
+		if err := tx.validate(); err != nil {
+			return conf, errors.Wrap(err, "tx config error")
+		}
+		conf.TX = &tx
+	}
+
+	// set packet-forwarder configuration
+	if p.Forwarder != nil {
+		if err := p.Forwarder.validate(); err != nil {
+			return conf, errors.Wrap(err, "packet-forwarder config error")
+		}
+		fwd := *p.Forwarder
+		conf.Forwarder = &fwd
+	}
+
+	// set Class-B beacon configuration
+	if p.Beacon != nil {
+		beacon := *p.Beacon
+		if beacon.Period != 0 && p.Band != "" {
+			if err := beacon.setBandDefaults(p.Band); err != nil {
+				return conf, errors.Wrap(err, "set beacon band defaults error")
+			}
+		}
+		if err := beacon.validate(); err != nil {
+			return conf, errors.Wrap(err, "beacon config error")
+		}
+		conf.Beacon = &beacon
+	}
+
+	// set GPS and location configuration
+	if p.Location != nil {
+		if err := p.Location.validate(); err != nil {
+			return conf, errors.Wrap(err, "location config error")
+		}
+		location := *p.Location
+		conf.Location = &location
+	}
+
+	// get the radio types, allowed frequency ranges, clock source and TX
+	// radio
+	hw, clkSrc, err := p.radioHardware()
+	if err != nil {
+		return conf, errors.Wrap(err, "get radio hardware error")
+	}
+	txRadio := getTXRadio(conf.TX, hw)
+	for i := range conf.Radios {
+		conf.Radios[i].Type = p.Radios[i].Type
+	}
+
+	// convert the channels (Hz and typed modulation parameters)
+	var channels []Channel
+	for _, c := range configResp.Channels {
+		channel, err := channelFromGW(c)
+		if err != nil {
+			return conf, errors.Wrap(err, "channel error")
+		}
+		channels = append(channels, channel)
+	}
+
+	// make sure the channels are sorted by the minimum radio center frequency
+	channelsCopy := make([]Channel, len(channels))
+	copy(channelsCopy, channels)
+	sort.Sort(channelByMinRadioCenterFrequency(channelsCopy))
+
+	// define the radios and their center frequency
+	for _, c := range channelsCopy {
+		var placed bool
+		for i, r := range conf.Radios {
+			// the radio does not support the channel frequency
+			if !hw[i].covers(c.minFreq(), c.maxFreq()) {
+				continue
+			}
+
+			// the radio is not defined yet, use it
+			if !r.Enable {
+				freq, ok := hw[i].radioCenterFreq(c.minRadioCenterFreq(), c.minFreq(), c.maxFreq(), c.radioBandwidth())
+				if !ok {
+					continue
+				}
+				conf.Radios[i].Enable = true
+				conf.Radios[i].Freq = freq
+				placed = true
+				break
+			}
+
+			if c.coveredBy(r.Freq) {
+				placed = true
+				break
+			}
+		}
+
+		if !placed {
+			return conf, fmt.Errorf("channel %d Hz (%d Hz bandwidth) can not be covered by the radios (out of range of the radio hardware or too many radios required)", c.Freq, c.Bandwidth)
+		}
+	}
+
+	// the clock source and TX radio must always be enabled
+	enableRequiredRadios(&conf, hw, clkSrc, txRadio)
+
+	// assign channels
+	for _, c := range channels {
+		var radio int
+
+		// get the radio covering the channel frequency
+		for i, r := range conf.Radios {
+			if r.Enable && hw[i].covers(c.minFreq(), c.maxFreq()) && c.coveredBy(r.Freq) {
+				radio = i
+				break
+			}
+		}
+
+		if c.Modulation == FSKModulation {
+			// FSK channel
+			if conf.FSKChannelConfig.Enable {
+				return conf, errors.New("FSK channel already configured")
+			}
+
+			conf.FSKChannelConfig = FSKChannelConfig{
+				Enable:        true,
+				Radio:         radio,
+				IF:            c.Freq - conf.Radios[radio].Freq,
+				Bandwidth:     c.Bandwidth,
+				DataRate:      c.BitRate,
+				FreqDeviation: c.FreqDeviation,
+				Freq:          c.Freq,
+			}
+
+		} else if len(c.SpreadFactors) == 1 {
+			// LoRa STD (single SF) channel
+			if conf.LoRaSTDChannelConfig.Enable {
+				return conf, errors.New("LoRa std channel already configured")
+			}
+
+			conf.LoRaSTDChannelConfig = LoRaSTDChannelConfig{
+				Enable:       true,
+				Radio:        radio,
+				IF:           c.Freq - conf.Radios[radio].Freq,
+				Bandwidth:    c.Bandwidth,
+				SpreadFactor: c.SpreadFactors[0],
+				Freq:         c.Freq,
+			}
+
+		} else {
+			// LoRa multi-SF channels
+			multiSFChannels = append(multiSFChannels, MultiSFChannelConfig{
+				Enable:        true,
+				Radio:         radio,
+				IF:            c.Freq - conf.Radios[radio].Freq,
+				Freq:          c.Freq,
+				SpreadFactors: restrictedSpreadFactors(c.SpreadFactors),
+			})
+		}
+	}
+
+	// assign the multi-SF channels to their (previous) slots
+	var previousSlots [channelCount]int
+	if p.PreviousConfigFile != "" {
+		if previousSlots, err = loadMultiSFSlots(p.PreviousConfigFile); err != nil {
+			return conf, errors.Wrap(err, "load previous multi-SF channel slots error")
+		}
+	}
+	if conf.MultiSFChannels, err = assignMultiSFSlots(multiSFChannels, previousSlots); err != nil {
+		return conf, err
+	}
+
+	// annotate the channels with the regional policy
+	if p.Band != "" {
+		if conf.Warnings, err = evaluatePolicy(p.Band, p.DwellTime, channels, &conf); err != nil {
+			return conf, errors.Wrap(err, "evaluate regional policy error")
+		}
+	}
+
+	// make sure the downlink frequencies are reachable by the TX radio
+	if p.Band != "" && txRadio != -1 {
+		if txRadio, err = placeTXRadio(p.Band, &conf, hw, txRadio); err != nil {
+			return conf, errors.Wrap(err, "place tx radio error")
+		}
+		enableRequiredRadios(&conf, hw, txRadio)
+	}
+
+	// set LBT configuration (covering the planned channels)
+	warning, err := checkLBTRequired(p.Band, p.LBT)
+	if err != nil {
+		return conf, errors.Wrap(err, "check lbt error")
+	}
+	if warning != "" {
+		conf.Warnings = append(conf.Warnings, warning)
+	}
+	if p.LBT != nil {
+		lbt, err := planLBT(*p.LBT, p.Band, conf)
+		if err != nil {
+			return conf, errors.Wrap(err, "plan lbt error")
+		}
+		conf.LBT = &lbt
+	}
+
+	return conf, nil
+}
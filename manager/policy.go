@@ -0,0 +1,158 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brocaar/lorawan"
+	"github.com/brocaar/lorawan/band"
+	"github.com/pkg/errors"
+)
+
+// restrictiveDutyCycle defines the duty-cycle (percent) below which
+// multiple channels within the same sub-band result in a warning.
+const restrictiveDutyCycle = 1.0
+
+// dwellTime400ms defines the dwell time (max. transmit duration) limit.
+const dwellTime400ms = 400 * time.Millisecond
+
+// subBand defines a regulatory sub-band, its duty-cycle limit and max.
+// EIRP (dBm).
+type subBand struct {
+	name      string
+	minFreq   int
+	maxFreq   int
+	dutyCycle float64
+	maxEIRP   int
+}
+
+// bandSubBands defines the (ETSI) sub-bands per band, for the bands
+// imposing a duty-cycle limit.
+var bandSubBands = map[band.Name][]subBand{
+	band.EU_863_870: {
+		{name: "h1.3", minFreq: 863000000, maxFreq: 865000000, dutyCycle: 0.1, maxEIRP: 16},
+		{name: "h1.4", minFreq: 865000000, maxFreq: 868000000, dutyCycle: 1, maxEIRP: 16},
+		{name: "h1.5", minFreq: 868000000, maxFreq: 868600000, dutyCycle: 1, maxEIRP: 16},
+		{name: "h1.6", minFreq: 868700000, maxFreq: 869200000, dutyCycle: 0.1, maxEIRP: 16},
+		{name: "h1.7", minFreq: 869400000, maxFreq: 869650000, dutyCycle: 10, maxEIRP: 27},
+		{name: "h1.9", minFreq: 869700000, maxFreq: 870000000, dutyCycle: 1, maxEIRP: 16},
+	},
+	band.EU_433: {
+		{name: "h1.4", minFreq: 433050000, maxFreq: 434790000, dutyCycle: 1, maxEIRP: 12},
+	},
+	band.CN_779_787: {
+		{name: "779-787", minFreq: 779000000, maxFreq: 787000000, dutyCycle: 1, maxEIRP: 12},
+	},
+}
+
+// ChannelPolicy contains the regional policy of a planned channel.
+type ChannelPolicy struct {
+	// SubBand contains the name of the sub-band (empty when the band does
+	// not define sub-bands).
+	SubBand string
+
+	// DutyCycle contains the duty-cycle limit of the sub-band (percent, 0
+	// when there is no duty-cycle limit).
+	DutyCycle float64
+
+	// UplinkDwellTime and DownlinkDwellTime contain the dwell time limit
+	// (0 when there is no dwell time limit).
+	UplinkDwellTime   time.Duration
+	DownlinkDwellTime time.Duration
+}
+
+// getChannelPolicy returns the regional policy for the given frequency.
+func getChannelPolicy(name band.Name, dt lorawan.DwellTime, freq int) (ChannelPolicy, error) {
+	var p ChannelPolicy
+
+	if subBands, ok := bandSubBands[name]; ok {
+		found := false
+		for _, sb := range subBands {
+			if freq >= sb.minFreq && freq <= sb.maxFreq {
+				p.SubBand = sb.name
+				p.DutyCycle = sb.dutyCycle
+				found = true
+				break
+			}
+		}
+		if !found {
+			return p, fmt.Errorf("frequency %d is not within a sub-band of band %s", freq, name)
+		}
+	}
+
+	switch name {
+	case band.US_902_928:
+		p.UplinkDwellTime = dwellTime400ms
+	case band.AS_923:
+		if dt == lorawan.DwellTime400ms {
+			p.UplinkDwellTime = dwellTime400ms
+			p.DownlinkDwellTime = dwellTime400ms
+		}
+	}
+
+	return p, nil
+}
+
+// evaluatePolicy annotates the planned channels with their regional policy
+// and returns the policy warnings for the given channels. Note that the
+// warnings do not fail the plan, as the channels are configured by the
+// gateway-server.
+func evaluatePolicy(name band.Name, dt lorawan.DwellTime, channels []Channel, conf *GatewayConfiguration) ([]string, error) {
+	var warnings []string
+
+	bandConfig, err := band.GetConfig(name, false, dt)
+	if err != nil {
+		return nil, errors.Wrap(err, "get band config error")
+	}
+
+	annotate := func(freq int) *ChannelPolicy {
+		p, err := getChannelPolicy(name, dt, freq)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+		}
+		return &p
+	}
+
+	subBandChannels := make(map[string][]int)
+	for i := range conf.MultiSFChannels {
+		if c := &conf.MultiSFChannels[i]; c.Enable {
+			c.Policy = annotate(c.Freq)
+			subBandChannels[c.Policy.SubBand] = append(subBandChannels[c.Policy.SubBand], c.Freq)
+		}
+	}
+	if c := &conf.LoRaSTDChannelConfig; c.Enable {
+		c.Policy = annotate(c.Freq)
+		subBandChannels[c.Policy.SubBand] = append(subBandChannels[c.Policy.SubBand], c.Freq)
+	}
+	if c := &conf.FSKChannelConfig; c.Enable {
+		c.Policy = annotate(c.Freq)
+		subBandChannels[c.Policy.SubBand] = append(subBandChannels[c.Policy.SubBand], c.Freq)
+	}
+
+	// warn for multiple channels in a sub-band with a restrictive duty-cycle
+	for _, sb := range bandSubBands[name] {
+		if freqs := subBandChannels[sb.name]; sb.dutyCycle < restrictiveDutyCycle && len(freqs) > 1 {
+			warnings = append(warnings, fmt.Sprintf("%d channels %v share sub-band %s with a duty-cycle of %g%%", len(freqs), freqs, sb.name, sb.dutyCycle))
+		}
+	}
+
+	// warn for spread-factors that are not allowed (e.g. because of the
+	// dwell time)
+	for _, c := range channels {
+		if c.Modulation != LoRaModulation {
+			continue
+		}
+		for _, sf := range c.SpreadFactors {
+			dr, err := bandConfig.GetDataRate(band.DataRate{
+				Modulation:   band.LoRaModulation,
+				SpreadFactor: sf,
+				Bandwidth:    c.Bandwidth / 1000,
+			})
+			if err != nil || (dr < len(bandConfig.MaxPayloadSize) && bandConfig.MaxPayloadSize[dr].N == 0) {
+				warnings = append(warnings, fmt.Sprintf("channel %d: SF%d / %d kHz is not an allowed data-rate of band %s", c.Freq, sf, c.Bandwidth/1000, name))
+			}
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+	"github.com/brocaar/lorawan/band"
+)
+
+func TestChannelPolicy(t *testing.T) {
+	Convey("Given a DefaultPlanner for EU_863_870 and the default channels", t, func() {
+		p := DefaultPlanner{Band: band.EU_863_870}
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+		for _, f := range []int32{868100000, 868300000, 868500000} {
+			resp.Channels = append(resp.Channels, &gw.Channel{
+				Modulation:    gw.Modulation_LORA,
+				Frequency:     f,
+				Bandwidth:     125,
+				SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+			})
+		}
+
+		Convey("Then the channels are annotated with the sub-band and duty-cycle", func() {
+			conf, err := p.Plan(&resp)
+			So(err, ShouldBeNil)
+			So(conf.Warnings, ShouldBeEmpty)
+			So(conf.MultiSFChannels[0].Policy, ShouldResemble, &ChannelPolicy{SubBand: "h1.5", DutyCycle: 1})
+			So(conf.MultiSFChannels[3].Policy, ShouldBeNil)
+		})
+
+		Convey("When two channels are planned in the h1.6 (0.1%) sub-band", func() {
+			for _, f := range []int32{868800000, 869000000} {
+				resp.Channels = append(resp.Channels, &gw.Channel{
+					Modulation:    gw.Modulation_LORA,
+					Frequency:     f,
+					Bandwidth:     125,
+					SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+				})
+			}
+
+			Convey("Then a duty-cycle warning is returned", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Warnings, ShouldResemble, []string{"2 channels [868800000 869000000] share sub-band h1.6 with a duty-cycle of 0.1%"})
+				So(conf.MultiSFChannels[3].Policy, ShouldResemble, &ChannelPolicy{SubBand: "h1.6", DutyCycle: 0.1})
+			})
+		})
+
+		Convey("When a channel is planned outside the sub-bands", func() {
+			resp.Channels[2].Frequency = 869300000
+
+			Convey("Then a warning is returned", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Warnings, ShouldResemble, []string{"frequency 869300000 is not within a sub-band of band EU_863_870"})
+			})
+		})
+	})
+
+	Convey("Given a DefaultPlanner for US_902_928", t, func() {
+		p := DefaultPlanner{Band: band.US_902_928}
+		resp := gw.GetConfigurationResponse{
+			UpdatedAt: time.Now().Format(time.RFC3339Nano),
+			Channels: []*gw.Channel{
+				{
+					Modulation:    gw.Modulation_LORA,
+					Frequency:     902300000,
+					Bandwidth:     125,
+					SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+				},
+			},
+		}
+
+		Convey("Then the channel is annotated with the dwell time", func() {
+			conf, err := p.Plan(&resp)
+			So(err, ShouldBeNil)
+			So(conf.MultiSFChannels[0].Policy, ShouldResemble, &ChannelPolicy{UplinkDwellTime: 400 * time.Millisecond})
+		})
+
+		Convey("Then the spread-factors which are not allowed return a warning", func() {
+			conf, err := p.Plan(&resp)
+			So(err, ShouldBeNil)
+			So(conf.Warnings, ShouldResemble, []string{
+				"channel 902300000: SF11 / 125 kHz is not an allowed data-rate of band US_902_928",
+				"channel 902300000: SF12 / 125 kHz is not an allowed data-rate of band US_902_928",
+			})
+		})
+	})
+
+	Convey("Given AS_923 with the 400ms dwell time", t, func() {
+		Convey("Then the uplink and downlink dwell time are set", func() {
+			p, err := getChannelPolicy(band.AS_923, lorawan.DwellTime400ms, 923200000)
+			So(err, ShouldBeNil)
+			So(p, ShouldResemble, ChannelPolicy{UplinkDwellTime: 400 * time.Millisecond, DownlinkDwellTime: 400 * time.Millisecond})
+		})
+	})
+}
@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Supported radio types.
+const (
+	RadioTypeSX1255 = "SX1255"
+	RadioTypeSX1257 = "SX1257"
+	RadioTypeSX1250 = "SX1250"
+)
+
+// radioTypeFreqRange defines the frequency range (Hz) supported by each
+// radio type.
+var radioTypeFreqRange = map[string]struct {
+	min int
+	max int
+}{
+	RadioTypeSX1255: {min: 400000000, max: 510000000},
+	RadioTypeSX1257: {min: 862000000, max: 1020000000},
+	RadioTypeSX1250: {min: 150000000, max: 960000000},
+}
+
+// RadioHardwareConfig contains the hardware configuration of a radio.
+type RadioHardwareConfig struct {
+	// Type defines the radio type (SX1255, SX1257 or SX1250). When set, it
+	// overrides the radio_N.type of the base configuration file.
+	Type string
+
+	// FreqMin and FreqMax define the allowed frequency range (Hz) of the
+	// radio, e.g. because of the RF front-end or SAW filter. When left 0,
+	// the frequency range of the radio type is used.
+	FreqMin int
+	FreqMax int
+
+	// tx, txFreqMin and txFreqMax contain the TX settings (tx_enable,
+	// tx_freq_min and tx_freq_max) of the base configuration file.
+	tx        bool
+	txFreqMin int
+	txFreqMax int
+}
+
+// validate validates the radio hardware configuration.
+func (c RadioHardwareConfig) validate() error {
+	if _, ok := radioTypeFreqRange[c.Type]; c.Type != "" && !ok {
+		return fmt.Errorf("invalid radio type: %s", c.Type)
+	}
+	if (c.FreqMin != 0 || c.FreqMax != 0) && c.FreqMin >= c.FreqMax {
+		return fmt.Errorf("radio frequency min (%d) must be less than max (%d)", c.FreqMin, c.FreqMax)
+	}
+	return nil
+}
+
+// freqRange returns the allowed frequency range of the radio. When no
+// range is known, 0, 0 is returned.
+func (c RadioHardwareConfig) freqRange() (int, int) {
+	if c.FreqMin != 0 || c.FreqMax != 0 {
+		return c.FreqMin, c.FreqMax
+	}
+	r := radioTypeFreqRange[c.Type]
+	return r.min, r.max
+}
+
+// covers returns true when the given frequency range is within the allowed
+// frequency range of the radio.
+func (c RadioHardwareConfig) covers(min, max int) bool {
+	freqMin, freqMax := c.freqRange()
+	if freqMin == 0 && freqMax == 0 {
+		return true
+	}
+	return min >= freqMin && max <= freqMax
+}
+
+// clampFreq returns the given frequency, limited to the allowed frequency
+// range of the radio.
+func (c RadioHardwareConfig) clampFreq(freq int) int {
+	freqMin, freqMax := c.freqRange()
+	if freqMin == 0 && freqMax == 0 {
+		return freq
+	}
+	if freq < freqMin {
+		return freqMin
+	}
+	if freq > freqMax {
+		return freqMax
+	}
+	return freq
+}
+
+// radioCenterFreq returns the radio center frequency closest to the given
+// (preferred) center frequency, which is within the allowed frequency range
+// of the radio and still covers the given channel. It returns false when
+// there is no such frequency.
+func (c RadioHardwareConfig) radioCenterFreq(freq, channelMin, channelMax, radioBandwidth int) (int, bool) {
+	if !c.covers(channelMin, channelMax) {
+		return 0, false
+	}
+
+	freq = c.clampFreq(freq)
+	if channelMin < freq-(radioBandwidth/2) || channelMax > freq+(radioBandwidth/2) {
+		return 0, false
+	}
+	return freq, true
+}
+
+// getRadioHardware returns the radio hardware configuration of the given
+// SX1301_conf (radio_N.type and the radio_N TX settings).
+func getRadioHardware(sx1301Conf map[string]interface{}) ([radioCount]RadioHardwareConfig, error) {
+	var out [radioCount]RadioHardwareConfig
+
+	for i := range out {
+		radio, ok := sx1301Conf[fmt.Sprintf("radio_%d", i)].(map[string]interface{})
+		if !ok {
+			return out, fmt.Errorf("expected radio_%d to be of type map[string]interface{}, got %T", i, sx1301Conf[fmt.Sprintf("radio_%d", i)])
+		}
+		if t, ok := radio["type"].(string); ok {
+			out[i].Type = t
+		}
+		if tx, ok := radio["tx_enable"].(bool); ok {
+			out[i].tx = tx
+		}
+		if f, ok := radio["tx_freq_min"].(float64); ok {
+			out[i].txFreqMin = int(f)
+		}
+		if f, ok := radio["tx_freq_max"].(float64); ok {
+			out[i].txFreqMax = int(f)
+		}
+		if err := out[i].validate(); err != nil {
+			return out, errors.Wrapf(err, "radio_%d", i)
+		}
+	}
+
+	return out, nil
+}
+
+// getClockSource returns the radio providing the clock to the concentrator
+// (clksrc) of the given SX1301_conf. It returns -1 when not set.
+func getClockSource(sx1301Conf map[string]interface{}) (int, error) {
+	v, ok := sx1301Conf["clksrc"]
+	if !ok {
+		return -1, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("expected clksrc to be of type float64, got %T", v)
+	}
+	if f < 0 || int(f) >= radioCount {
+		return 0, fmt.Errorf("invalid clksrc: %g", f)
+	}
+	return int(f), nil
+}
@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+func TestRadioHardware(t *testing.T) {
+	Convey("Given a CN470 configuration response", t, func() {
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+		for _, f := range []int32{470300000, 470500000, 470700000, 470900000, 471100000, 471300000, 471500000, 471700000} {
+			resp.Channels = append(resp.Channels, &gw.Channel{
+				Modulation:    gw.Modulation_LORA,
+				Frequency:     f,
+				Bandwidth:     125,
+				SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+			})
+		}
+
+		Convey("Given a DefaultPlanner with SX1255 radios", func() {
+			p := DefaultPlanner{
+				Radios: [radioCount]RadioHardwareConfig{
+					{Type: RadioTypeSX1255},
+					{Type: RadioTypeSX1255},
+				},
+			}
+
+			Convey("Then the radios are planned and the radio type is set", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Radios, ShouldResemble, [radioCount]RadioConfig{
+					{Enable: true, Freq: 470700000, Type: RadioTypeSX1255},
+					{Enable: true, Freq: 471700000, Type: RadioTypeSX1255},
+				})
+			})
+		})
+
+		Convey("Given a DefaultPlanner with the SX1257 base configuration file", func() {
+			p := DefaultPlanner{BaseConfigFile: "test/test.json"}
+
+			Convey("Then an out of range error is returned", func() {
+				_, err := p.Plan(&resp)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "channel 470300000 Hz (125000 Hz bandwidth) can not be covered by the radios")
+			})
+		})
+	})
+
+	Convey("Given an EU868 configuration response", t, func() {
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+		for _, f := range []int32{868100000, 868300000, 868500000} {
+			resp.Channels = append(resp.Channels, &gw.Channel{
+				Modulation:    gw.Modulation_LORA,
+				Frequency:     f,
+				Bandwidth:     125,
+				SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+			})
+		}
+
+		Convey("Given radio_0 has a SAW filter for 863 - 865 MHz", func() {
+			p := DefaultPlanner{
+				BaseConfigFile: "test/test.json",
+				Radios: [radioCount]RadioHardwareConfig{
+					{FreqMin: 863000000, FreqMax: 865000000},
+				},
+			}
+
+			Convey("Then the channels are planned on radio_1", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Radios, ShouldResemble, [radioCount]RadioConfig{
+					{Enable: true, Freq: 865000000},
+					{Enable: true, Freq: 868500000},
+				})
+				for _, c := range conf.MultiSFChannels[:3] {
+					So(c.Radio, ShouldEqual, 1)
+				}
+			})
+		})
+
+		Convey("Given the radio center frequency is limited by the frequency range", func() {
+			p := DefaultPlanner{
+				Radios: [radioCount]RadioHardwareConfig{
+					{FreqMin: 867000000, FreqMax: 868300000},
+					{Type: RadioTypeSX1255},
+				},
+			}
+
+			Convey("Then the radio center frequency is kept within the range", func() {
+				resp.Channels = resp.Channels[:1]
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Radios[0], ShouldResemble, RadioConfig{Enable: true, Freq: 868300000})
+				So(conf.Radios[1].Enable, ShouldBeFalse)
+			})
+
+			Convey("Then a channel outside the range returns an error", func() {
+				_, err := p.Plan(&resp)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "channel 868300000 Hz (125000 Hz bandwidth) can not be covered by the radios")
+			})
+		})
+
+		Convey("Given a DefaultPlanner with the SX1302 (SX1250) base configuration file", func() {
+			p := DefaultPlanner{BaseConfigFile: "test/test_sx1302.json"}
+
+			Convey("Then the channels are planned", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Radios[0], ShouldResemble, RadioConfig{Enable: true, Freq: 868500000})
+				for _, c := range conf.MultiSFChannels[:3] {
+					So(c.Enable, ShouldBeTrue)
+					So(c.Radio, ShouldEqual, 0)
+				}
+			})
+		})
+
+		Convey("Then an invalid radio type returns an error", func() {
+			p := DefaultPlanner{
+				Radios: [radioCount]RadioHardwareConfig{
+					{Type: "SX1258"},
+				},
+			}
+			_, err := p.Plan(&resp)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given the test base configuration", t, func() {
+		conf, err := loadConfigFile("test/test.json")
+		So(err, ShouldBeNil)
+
+		Convey("Then the radio types are returned", func() {
+			hw, err := getRadioHardware(conf.SX1301Conf)
+			So(err, ShouldBeNil)
+			So(hw, ShouldResemble, [radioCount]RadioHardwareConfig{
+				{Type: RadioTypeSX1257, tx: true, txFreqMin: 863000000, txFreqMax: 870000000},
+				{Type: RadioTypeSX1257},
+			})
+		})
+
+		Convey("Then the SX1250 radio types of the SX1302 base configuration are returned", func() {
+			conf, err := loadConfigFile("test/test_sx1302.json")
+			So(err, ShouldBeNil)
+			hw, err := getRadioHardware(conf.SX1301Conf)
+			So(err, ShouldBeNil)
+			So(hw, ShouldResemble, [radioCount]RadioHardwareConfig{
+				{Type: RadioTypeSX1250, tx: true, txFreqMin: 863000000, txFreqMax: 870000000},
+				{Type: RadioTypeSX1250},
+			})
+		})
+
+		Convey("When merging a configuration with radio types", func() {
+			So(mergeConfig(lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}, conf, GatewayConfiguration{
+				Radios: [radioCount]RadioConfig{
+					{Enable: true, Freq: 433575000, Type: RadioTypeSX1255},
+					{Enable: false},
+				},
+			}), ShouldBeNil)
+
+			Convey("Then only the configured radio type is updated", func() {
+				So(conf.SX1301Conf["radio_0"].(map[string]interface{})["type"], ShouldEqual, RadioTypeSX1255)
+				So(conf.SX1301Conf["radio_1"].(map[string]interface{})["type"], ShouldEqual, RadioTypeSX1257)
+			})
+		})
+	})
+}
@@ -0,0 +1,213 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// ChangeKind classifies the change between two planned configurations.
+type ChangeKind int
+
+// Change kinds, in order of impact.
+const (
+	// ChangeNone indicates that the written configuration did not change.
+	ChangeNone ChangeKind = iota
+
+	// ChangeGatewayConf indicates that only the gateway_conf settings
+	// (packet-forwarder, beacon and location settings) changed. These
+	// changes can be applied by reloading the packet-forwarder.
+	ChangeGatewayConf
+
+	// ChangeConcentrator indicates that the concentrator settings (radios,
+	// channels, TX or LBT) changed. These changes require the
+	// concentrator to be re-initialized and thus a full restart.
+	ChangeConcentrator
+)
+
+// String implements fmt.Stringer.
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeNone:
+		return "none"
+	case ChangeGatewayConf:
+		return "gateway_conf"
+	case ChangeConcentrator:
+		return "concentrator"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// classifyChange returns the kind of change between the previously applied
+// and the new configuration. When the previously applied configuration is
+// unknown (nil), ChangeConcentrator is returned.
+func classifyChange(previous *GatewayConfiguration, conf GatewayConfiguration) ChangeKind {
+	if previous == nil {
+		return ChangeConcentrator
+	}
+	if !reflect.DeepEqual(concentratorConfig(*previous), concentratorConfig(conf)) {
+		return ChangeConcentrator
+	}
+	if !reflect.DeepEqual(gatewayConfConfig(*previous), gatewayConfConfig(conf)) {
+		return ChangeGatewayConf
+	}
+	return ChangeNone
+}
+
+// concentratorConfig returns the concentrator settings (SX1301_conf) of the
+// given configuration. The policy annotations are removed as these are not
+// written.
+func concentratorConfig(c GatewayConfiguration) GatewayConfiguration {
+	out := GatewayConfiguration{
+		Radios:               c.Radios,
+		LoRaSTDChannelConfig: c.LoRaSTDChannelConfig,
+		FSKChannelConfig:     c.FSKChannelConfig,
+		TX:                   c.TX,
+		LBT:                  c.LBT,
+	}
+	for i, ch := range c.MultiSFChannels {
+		ch.Policy = nil
+		out.MultiSFChannels[i] = ch
+	}
+	out.LoRaSTDChannelConfig.Policy = nil
+	out.FSKChannelConfig.Policy = nil
+	return out
+}
+
+// gatewayConfConfig returns the gateway_conf settings of the given
+// configuration.
+func gatewayConfConfig(c GatewayConfiguration) GatewayConfiguration {
+	return GatewayConfiguration{
+		Forwarder: c.Forwarder,
+		Beacon:    c.Beacon,
+		Location:  c.Location,
+	}
+}
+
+// Reloader reloads the packet-forwarder configuration, without
+// re-initializing the concentrator. It is used for changes that do not
+// require a full restart (see ChangeGatewayConf).
+type Reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// defaultSignalCheckDelay defines the default time to wait after sending the
+// reload signal before checking that the packet-forwarder is still running.
+const defaultSignalCheckDelay = 2 * time.Second
+
+// SignalReloader implements Reloader by sending a signal to the
+// packet-forwarder process. Note that this requires a packet-forwarder
+// which reloads its configuration on this signal, the stock lora_pkt_fwd
+// exits on SIGHUP.
+type SignalReloader struct {
+	// PIDFile defines the file containing the process ID of the
+	// packet-forwarder.
+	PIDFile string
+
+	// Signal defines the signal to send (default: SIGHUP).
+	Signal os.Signal
+
+	// CheckDelay defines the time to wait after sending the signal before
+	// checking that the packet-forwarder is still running (default: 2s).
+	CheckDelay time.Duration
+}
+
+// Reload sends the signal to the packet-forwarder process. When the
+// packet-forwarder is no longer running after the check delay (e.g. because
+// it exits on the signal), an error is returned so that the manager falls
+// back on a restart.
+func (r SignalReloader) Reload(ctx context.Context) error {
+	b, err := ioutil.ReadFile(r.PIDFile)
+	if err != nil {
+		return errors.Wrap(err, "read pid file error")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return errors.Wrap(err, "parse pid error")
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return errors.Wrap(err, "find process error")
+	}
+
+	sig := r.Signal
+	if sig == nil {
+		sig = syscall.SIGHUP
+	}
+
+	log.WithFields(log.Fields{
+		"pid":    pid,
+		"signal": sig,
+	}).Info("sending reload signal to packet-forwarder")
+
+	if err := p.Signal(sig); err != nil {
+		return errors.Wrap(err, "send signal error")
+	}
+
+	delay := r.CheckDelay
+	if delay == 0 {
+		delay = defaultSignalCheckDelay
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+	}
+
+	// signal 0 only checks the existence of the process
+	if err := p.Signal(syscall.Signal(0)); err != nil {
+		return errors.Wrapf(err, "packet-forwarder (pid %d) not running after the reload signal", pid)
+	}
+	return nil
+}
+
+// SocketReloader implements Reloader by writing a command to the control
+// (unix) socket of the packet-forwarder.
+type SocketReloader struct {
+	// Address defines the path of the control socket.
+	Address string
+
+	// Command defines the command to write (default: reload).
+	Command string
+}
+
+// Reload writes the reload command to the control socket.
+func (r SocketReloader) Reload(ctx context.Context) error {
+	cmd := r.Command
+	if cmd == "" {
+		cmd = "reload"
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", r.Address)
+	if err != nil {
+		return errors.Wrap(err, "dial control socket error")
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	log.WithFields(log.Fields{
+		"address": r.Address,
+		"command": cmd,
+	}).Info("writing reload command to packet-forwarder control socket")
+
+	if _, err := conn.Write([]byte(cmd + "\n")); err != nil {
+		return errors.Wrap(err, "write control socket error")
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package manager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+// testPlanner implements a Planner returning the configured configuration.
+type testPlanner struct {
+	conf *GatewayConfiguration
+}
+
+func (p testPlanner) Plan(resp *gw.GetConfigurationResponse) (GatewayConfiguration, error) {
+	conf := *p.conf
+	ts, err := time.Parse(time.RFC3339Nano, resp.UpdatedAt)
+	conf.UpdatedAt = ts
+	return conf, err
+}
+
+type testWriter struct{}
+
+func (testWriter) Write(ctx context.Context, mac lorawan.EUI64, conf GatewayConfiguration) error {
+	return nil
+}
+
+// testRestarter implements Restarter and Reloader, counting the restarts
+// and reloads.
+type testRestarter struct {
+	restarts    int
+	reloads     int
+	reloadError error
+}
+
+func (r *testRestarter) Restart(ctx context.Context) error {
+	r.restarts++
+	return nil
+}
+
+func (r *testRestarter) Reload(ctx context.Context) error {
+	r.reloads++
+	return r.reloadError
+}
+
+func TestClassifyChange(t *testing.T) {
+	Convey("Given a planned configuration", t, func() {
+		conf := GatewayConfiguration{
+			Radios: [radioCount]RadioConfig{{Enable: true, Freq: 868500000}},
+			MultiSFChannels: [channelCount]MultiSFChannelConfig{
+				{Enable: true, IF: -400000, Freq: 868100000, Policy: &ChannelPolicy{SubBand: "h1.5", DutyCycle: 1}},
+			},
+			Forwarder: &ForwarderConfig{ServerAddress: "localhost"},
+		}
+
+		Convey("Then a configuration without previous configuration is a concentrator change", func() {
+			So(classifyChange(nil, conf), ShouldEqual, ChangeConcentrator)
+		})
+
+		Convey("Then an equal configuration (ignoring the policy annotations) is not a change", func() {
+			next := conf
+			next.UpdatedAt = time.Now()
+			next.MultiSFChannels[0].Policy = nil
+			So(classifyChange(&conf, next), ShouldEqual, ChangeNone)
+		})
+
+		Convey("Then a changed gateway_conf setting is a gateway_conf change", func() {
+			next := conf
+			next.Forwarder = &ForwarderConfig{ServerAddress: "example.com"}
+			So(classifyChange(&conf, next), ShouldEqual, ChangeGatewayConf)
+		})
+
+		Convey("Then a changed channel is a concentrator change", func() {
+			next := conf
+			next.Forwarder = &ForwarderConfig{ServerAddress: "example.com"}
+			next.MultiSFChannels[1] = MultiSFChannelConfig{Enable: true, IF: -200000, Freq: 868300000}
+			So(classifyChange(&conf, next), ShouldEqual, ChangeConcentrator)
+		})
+	})
+}
+
+func TestManagerReload(t *testing.T) {
+	Convey("Given a Manager with a Reloader", t, func() {
+		client := testGatewayClient{
+			GetConfigurationRequestChan: make(chan gw.GetConfigurationRequest, 100),
+			GetConfigurationResponse: gw.GetConfigurationResponse{
+				UpdatedAt: time.Now().Format(time.RFC3339Nano),
+			},
+		}
+		conf := GatewayConfiguration{
+			Radios:    [radioCount]RadioConfig{{Enable: true, Freq: 868500000}},
+			Forwarder: &ForwarderConfig{ServerAddress: "localhost"},
+		}
+		restarter := testRestarter{}
+
+		m, err := New(lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8},
+			WithConfigSource(GatewayClientSource{Client: &client}),
+			WithPlanner(testPlanner{conf: &conf}),
+			WithWriter(testWriter{}),
+			WithRestarter(&restarter),
+			WithReloader(&restarter),
+		)
+		So(err, ShouldBeNil)
+
+		update := func() {
+			client.GetConfigurationResponse.UpdatedAt = time.Now().Add(time.Second).Format(time.RFC3339Nano)
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+		}
+
+		Convey("Then the first update restarts the packet-forwarder", func() {
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+			So(restarter.restarts, ShouldEqual, 1)
+			So(restarter.reloads, ShouldEqual, 0)
+
+			Convey("Then an unchanged configuration does not restart or reload", func() {
+				update()
+				So(restarter.restarts, ShouldEqual, 1)
+				So(restarter.reloads, ShouldEqual, 0)
+			})
+
+			Convey("Then a gateway_conf change reloads the packet-forwarder", func() {
+				conf.Forwarder = &ForwarderConfig{ServerAddress: "example.com"}
+				update()
+				So(restarter.restarts, ShouldEqual, 1)
+				So(restarter.reloads, ShouldEqual, 1)
+			})
+
+			Convey("Then a failing reload falls back on a restart", func() {
+				restarter.reloadError = errors.New("boom")
+				conf.Forwarder = &ForwarderConfig{ServerAddress: "example.com"}
+				update()
+				So(restarter.restarts, ShouldEqual, 2)
+				So(restarter.reloads, ShouldEqual, 1)
+			})
+
+			Convey("Then a radio change restarts the packet-forwarder", func() {
+				conf.Radios[0].Freq = 867500000
+				update()
+				So(restarter.restarts, ShouldEqual, 2)
+				So(restarter.reloads, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestReloaders(t *testing.T) {
+	Convey("Given a temp directory", t, func() {
+		tempDir, err := ioutil.TempDir("", "test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+
+		Convey("Given a SignalReloader with the pid file of the current process", func() {
+			pidFile := filepath.Join(tempDir, "pf.pid")
+			So(ioutil.WriteFile(pidFile, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0600), ShouldBeNil)
+
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, syscall.SIGHUP)
+			defer signal.Stop(sigChan)
+
+			Convey("Then Reload sends SIGHUP", func() {
+				So(SignalReloader{PIDFile: pidFile, CheckDelay: 10 * time.Millisecond}.Reload(context.Background()), ShouldBeNil)
+				So(<-sigChan, ShouldEqual, syscall.SIGHUP)
+			})
+		})
+
+		Convey("Given a SignalReloader with the pid file of a process exiting on SIGHUP", func() {
+			cmd := exec.Command("sleep", "10")
+			So(cmd.Start(), ShouldBeNil)
+			go cmd.Wait()
+			defer cmd.Process.Kill()
+
+			pidFile := filepath.Join(tempDir, "pf.pid")
+			So(ioutil.WriteFile(pidFile, []byte(fmt.Sprintf("%d\n", cmd.Process.Pid)), 0600), ShouldBeNil)
+
+			Convey("Then Reload returns an error", func() {
+				err := SignalReloader{PIDFile: pidFile, CheckDelay: 200 * time.Millisecond}.Reload(context.Background())
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "not running after the reload signal")
+			})
+		})
+
+		Convey("Then a SignalReloader with a missing pid file returns an error", func() {
+			So(SignalReloader{PIDFile: filepath.Join(tempDir, "missing.pid")}.Reload(context.Background()), ShouldNotBeNil)
+		})
+
+		Convey("Given a SocketReloader and a listening control socket", func() {
+			address := filepath.Join(tempDir, "pf.sock")
+			ln, err := net.Listen("unix", address)
+			So(err, ShouldBeNil)
+			defer ln.Close()
+
+			lineChan := make(chan string, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				lineChan <- line
+			}()
+
+			Convey("Then Reload writes the reload command", func() {
+				So(SocketReloader{Address: address}.Reload(context.Background()), ShouldBeNil)
+				So(<-lineChan, ShouldEqual, "reload\n")
+			})
+		})
+	})
+}
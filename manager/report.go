@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/brocaar/lorawan"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+)
+
+// ReportStatus defines the status of a report.
+type ReportStatus string
+
+// Report statuses.
+const (
+	ReportApplied ReportStatus = "applied"
+	ReportFailed  ReportStatus = "failed"
+)
+
+// Report contains the outcome of a configuration update, reported back to
+// the server.
+type Report struct {
+	MAC       lorawan.EUI64 `json:"mac"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	Status    ReportStatus  `json:"status"`
+
+	// Method contains the method used to activate the configuration
+	// (restart, reload or none).
+	Method string `json:"method,omitempty"`
+
+	// Radios and Channels contain the applied radio center frequencies and
+	// channel to radio / IF mapping.
+	Radios   []ReportRadio   `json:"radios,omitempty"`
+	Channels []ReportChannel `json:"channels,omitempty"`
+
+	// Warnings contains the regional policy warnings.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Error contains the validation, write or restart error (failed
+	// status only).
+	Error string `json:"error,omitempty"`
+}
+
+// ReportRadio contains the configuration of a radio.
+type ReportRadio struct {
+	Radio  int  `json:"radio"`
+	Enable bool `json:"enable"`
+	Freq   int  `json:"freq"`
+}
+
+// ReportChannel contains the configuration of a channel.
+type ReportChannel struct {
+	Name  string `json:"name"`
+	Radio int    `json:"radio"`
+	IF    int    `json:"if"`
+	Freq  int    `json:"freq"`
+}
+
+// newReport returns the report for the given configuration. When err is
+// set, the status is failed and the radios and channels are omitted.
+func newReport(mac lorawan.EUI64, conf GatewayConfiguration, method string, err error) Report {
+	r := Report{
+		MAC:       mac,
+		UpdatedAt: conf.UpdatedAt,
+		Status:    ReportApplied,
+		Method:    method,
+		Warnings:  conf.Warnings,
+	}
+	if err != nil {
+		r.Status = ReportFailed
+		r.Error = err.Error()
+		return r
+	}
+
+	for i, radio := range conf.Radios {
+		r.Radios = append(r.Radios, ReportRadio{Radio: i, Enable: radio.Enable, Freq: radio.Freq})
+	}
+	for i, c := range conf.MultiSFChannels {
+		if c.Enable {
+			r.Channels = append(r.Channels, ReportChannel{Name: fmt.Sprintf("chan_multiSF_%d", i), Radio: c.Radio, IF: c.IF, Freq: c.Freq})
+		}
+	}
+	if c := conf.LoRaSTDChannelConfig; c.Enable {
+		r.Channels = append(r.Channels, ReportChannel{Name: "chan_Lora_std", Radio: c.Radio, IF: c.IF, Freq: c.Freq})
+	}
+	if c := conf.FSKChannelConfig; c.Enable {
+		r.Channels = append(r.Channels, ReportChannel{Name: "chan_FSK", Radio: c.Radio, IF: c.IF, Freq: c.Freq})
+	}
+
+	return r
+}
+
+// Reporter reports the outcome of configuration updates back to the
+// server.
+type Reporter interface {
+	Report(ctx context.Context, r Report) error
+}
+
+// HTTPReporter implements Reporter by posting the report as JSON to the
+// configured URL.
+type HTTPReporter struct {
+	URL string
+
+	// Client defines the HTTP client (default: http.DefaultClient).
+	Client *http.Client
+
+	// Credentials defines the (optional) credentials used to set the
+	// Authorization header, e.g. a TokenCredentials or the JWT credentials
+	// of the gateway-server client.
+	Credentials credentials.PerRPCCredentials
+}
+
+// TokenCredentials implements the grpc credentials.PerRPCCredentials
+// interface for a static (bearer) token. The token is only sent over a
+// secure (https) connection.
+type TokenCredentials string
+
+// GetRequestMetadata returns the authorization metadata.
+func (t TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": string(t),
+	}, nil
+}
+
+// RequireTransportSecurity returns true, the token is never sent over an
+// insecure connection.
+func (t TokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// Report posts the given report.
+func (r HTTPReporter) Report(ctx context.Context, report Report) error {
+	if err := postJSON(ctx, r.Client, r.URL, r.Credentials, report); err != nil {
+		return errors.Wrap(err, "post report error")
+	}
+	return nil
+}
+
+// postJSON posts the given value as JSON to the given url. When set, the
+// credentials are used to set the Authorization header.
+func postJSON(ctx context.Context, client *http.Client, url string, creds credentials.PerRPCCredentials, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "marshal json error")
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "new request error")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if creds != nil {
+		if creds.RequireTransportSecurity() && req.URL.Scheme != "https" {
+			return fmt.Errorf("credentials require a secure (https) connection, refusing to post to %s", req.URL.Host)
+		}
+		md, err := creds.GetRequestMetadata(ctx, url)
+		if err != nil {
+			return errors.Wrap(err, "get request metadata error")
+		}
+		if token := md["authorization"]; token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "http request error")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected http status %s", resp.Status)
+	}
+	return nil
+}
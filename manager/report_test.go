@@ -0,0 +1,174 @@
+package manager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+// testReportServer implements a fake gateway-server, recording the posted
+// reports.
+type testReportServer struct {
+	sync.Mutex
+	reports       []Report
+	authorization []string
+}
+
+func (s *testReportServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var report Report
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.reports = append(s.reports, report)
+	s.authorization = append(s.authorization, r.Header.Get("Authorization"))
+}
+
+// testCredentials implements credentials.PerRPCCredentials.
+type testCredentials struct {
+	token string
+}
+
+func (c testCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": c.token}, nil
+}
+
+func (c testCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func TestReport(t *testing.T) {
+	Convey("Given a fake gateway-server and a Manager with an HTTPReporter", t, func() {
+		tempDir, err := ioutil.TempDir("", "test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+
+		server := testReportServer{}
+		httpServer := httptest.NewServer(&server)
+		defer httpServer.Close()
+
+		now := time.Now().UTC().Truncate(time.Second)
+		client := testGatewayClient{
+			GetConfigurationRequestChan: make(chan gw.GetConfigurationRequest, 100),
+			GetConfigurationResponse: gw.GetConfigurationResponse{
+				UpdatedAt: now.Format(time.RFC3339Nano),
+			},
+		}
+		for _, f := range []int32{868100000, 868300000, 868500000} {
+			client.GetConfigurationResponse.Channels = append(client.GetConfigurationResponse.Channels, &gw.Channel{
+				Modulation:    gw.Modulation_LORA,
+				Frequency:     f,
+				Bandwidth:     125,
+				SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+			})
+		}
+
+		mac := lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+		restarter := CommandRestarter{Command: "true"}
+		newManager := func() *Manager {
+			m, err := New(mac,
+				WithConfigSource(GatewayClientSource{Client: &client}),
+				WithPlanner(DefaultPlanner{BaseConfigFile: "test/test.json"}),
+				WithWriter(FileWriter{
+					BaseConfigFile:   "test/test.json",
+					OutputConfigFile: filepath.Join(tempDir, "out.json"),
+				}),
+				WithRestarter(&restarter),
+				WithReporter(HTTPReporter{
+					URL:         httpServer.URL,
+					Credentials: testCredentials{token: "secret"},
+				}),
+			)
+			So(err, ShouldBeNil)
+			return m
+		}
+
+		Convey("When the configuration is applied", func() {
+			So(newManager().ApplyOnce(context.Background()), ShouldBeNil)
+
+			Convey("Then the applied configuration is reported", func() {
+				So(server.reports, ShouldHaveLength, 1)
+				So(server.authorization[0], ShouldEqual, "Bearer secret")
+				So(server.reports[0], ShouldResemble, Report{
+					MAC:       mac,
+					UpdatedAt: now,
+					Status:    ReportApplied,
+					Method:    "restart",
+					Radios: []ReportRadio{
+						{Radio: 0, Enable: true, Freq: 868500000},
+						{Radio: 1, Enable: true, Freq: 868500000},
+					},
+					Channels: []ReportChannel{
+						{Name: "chan_multiSF_0", Radio: 0, IF: -400000, Freq: 868100000},
+						{Name: "chan_multiSF_1", Radio: 0, IF: -200000, Freq: 868300000},
+						{Name: "chan_multiSF_2", Radio: 0, IF: 0, Freq: 868500000},
+					},
+				})
+			})
+		})
+
+		Convey("When the configuration is invalid", func() {
+			client.GetConfigurationResponse.Channels[0].Bandwidth = 100
+			So(newManager().ApplyOnce(context.Background()), ShouldNotBeNil)
+
+			Convey("Then the validation error is reported", func() {
+				So(server.reports, ShouldHaveLength, 1)
+				So(server.reports[0].Status, ShouldEqual, ReportFailed)
+				So(server.reports[0].Error, ShouldContainSubstring, "invalid bandwidth")
+				So(server.reports[0].Radios, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the packet-forwarder restart fails", func() {
+			restarter.Command = "false"
+			So(newManager().ApplyOnce(context.Background()), ShouldNotBeNil)
+
+			Convey("Then the restart error is reported", func() {
+				So(server.reports, ShouldHaveLength, 1)
+				So(server.reports[0].Status, ShouldEqual, ReportFailed)
+				So(server.reports[0].Method, ShouldEqual, "restart")
+				So(server.reports[0].Error, ShouldContainSubstring, "invoke packet-forwarder restart error")
+			})
+		})
+	})
+
+	Convey("Given an HTTPReporter with credentials requiring transport security", t, func() {
+		r := HTTPReporter{URL: "http://localhost:1234/report", Credentials: secureTestCredentials{}}
+
+		Convey("Then reporting over http returns an error", func() {
+			So(r.Report(context.Background(), Report{}), ShouldNotBeNil)
+		})
+
+		Convey("Then a TokenCredentials requires transport security", func() {
+			r.Credentials = TokenCredentials("secret")
+			So(r.Report(context.Background(), Report{}), ShouldNotBeNil)
+
+			md, err := TokenCredentials("secret").GetRequestMetadata(context.Background())
+			So(err, ShouldBeNil)
+			So(md, ShouldResemble, map[string]string{"authorization": "secret"})
+		})
+	})
+}
+
+type secureTestCredentials struct {
+	testCredentials
+}
+
+func (c secureTestCredentials) RequireTransportSecurity() bool {
+	return true
+}
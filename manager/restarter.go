@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"os/exec"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Restarter restarts the packet-forwarder after the configuration has been
+// written.
+type Restarter interface {
+	Restart(ctx context.Context) error
+}
+
+// CommandRestarter implements Restarter by executing a command.
+type CommandRestarter struct {
+	Command string
+}
+
+// Restart executes the restart command.
+func (r CommandRestarter) Restart(ctx context.Context) error {
+	parts := strings.Fields(r.Command)
+	if len(parts) == 0 {
+		return errors.New("no packet-forwarder restart command configured")
+	}
+
+	var args []string
+	if len(parts) > 1 {
+		args = parts[1:len(parts)]
+	}
+
+	log.WithFields(log.Fields{
+		"cmd":  parts[0],
+		"args": args,
+	}).Info("invoking packet-forwarder restart command")
+
+	out, err := exec.CommandContext(ctx, parts[0], args...).Output()
+	if err != nil {
+		return errors.Wrap(err, "execute command error")
+	}
+	log.WithField("output", string(out)).Info("packet-forwarder restart command invoked")
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// Rollout metadata keys, set by the gateway-server in the header of the
+// GetConfiguration response.
+const (
+	rolloutPercentageKey = "rollout-percentage"
+	rolloutDeadlineKey   = "rollout-deadline"
+)
+
+// rolloutBuckets defines the number of rollout buckets.
+const rolloutBuckets = 100
+
+// Rollout defines a staged (canary) rollout of a configuration update.
+// The update is only activated by the gateways within the admitted
+// percentage, the other gateways stay on their previous configuration
+// until the percentage is increased or the deadline has passed.
+type Rollout struct {
+	// Percentage defines the percentage (0 - 100) of gateways admitted.
+	Percentage int
+
+	// Deadline defines the time after which all gateways are admitted
+	// (optional).
+	Deadline time.Time
+}
+
+// validate validates the rollout.
+func (r Rollout) validate() error {
+	if r.Percentage < 0 || r.Percentage > 100 {
+		return fmt.Errorf("rollout percentage must be between 0 and 100, got %d", r.Percentage)
+	}
+	return nil
+}
+
+// admits returns true when the gateway with the given rollout bucket is
+// admitted at the given time.
+func (r Rollout) admits(bucket int, now time.Time) bool {
+	if !r.Deadline.IsZero() && !now.Before(r.Deadline) {
+		return true
+	}
+	return bucket < r.Percentage*rolloutBuckets/100
+}
+
+// RolloutBucket returns the stable rollout bucket (0 - 99) of the given
+// gateway MAC. A rollout of n percent admits the buckets below n.
+func RolloutBucket(mac lorawan.EUI64) int {
+	h := fnv.New32a()
+	h.Write(mac[:])
+	return int(h.Sum32() % rolloutBuckets)
+}
+
+// RolloutSource is implemented by a ConfigSource which supports staged
+// rollouts. When implemented, it is used instead of GetConfiguration.
+type RolloutSource interface {
+	// GetConfigurationRollout returns the configuration for the given
+	// gateway MAC and the rollout of this configuration. The rollout is
+	// nil when the configuration must be activated immediately.
+	GetConfigurationRollout(ctx context.Context, mac lorawan.EUI64) (*gw.GetConfigurationResponse, *Rollout, error)
+}
+
+// rolloutFromMetadata returns the rollout from the given (header) metadata.
+// It returns nil when the metadata does not contain a rollout.
+func rolloutFromMetadata(md metadata.MD) (*Rollout, error) {
+	percentage := md[rolloutPercentageKey]
+	if len(percentage) == 0 {
+		return nil, nil
+	}
+
+	var r Rollout
+	var err error
+	if r.Percentage, err = strconv.Atoi(percentage[0]); err != nil {
+		return nil, errors.Wrap(err, "parse rollout percentage error")
+	}
+	if deadline := md[rolloutDeadlineKey]; len(deadline) != 0 {
+		if r.Deadline, err = time.Parse(time.RFC3339Nano, deadline[0]); err != nil {
+			return nil, errors.Wrap(err, "parse rollout deadline error")
+		}
+	}
+
+	return &r, r.validate()
+}
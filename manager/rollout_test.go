@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+// testRolloutSource implements RolloutSource.
+type testRolloutSource struct {
+	resp    gw.GetConfigurationResponse
+	rollout *Rollout
+}
+
+func (s *testRolloutSource) GetConfiguration(ctx context.Context, mac lorawan.EUI64) (*gw.GetConfigurationResponse, error) {
+	return &s.resp, nil
+}
+
+func (s *testRolloutSource) GetConfigurationRollout(ctx context.Context, mac lorawan.EUI64) (*gw.GetConfigurationResponse, *Rollout, error) {
+	return &s.resp, s.rollout, nil
+}
+
+// rolloutPending returns the value of the rollout pending gauge of the
+// given gateway.
+func rolloutPending(mac lorawan.EUI64) float64 {
+	var metric dto.Metric
+	So(configRolloutPending.WithLabelValues(mac.String()).Write(&metric), ShouldBeNil)
+	return metric.GetGauge().GetValue()
+}
+
+func TestRollout(t *testing.T) {
+	Convey("Given 10000 gateway MACs", t, func() {
+		var macs []lorawan.EUI64
+		for i := 0; i < 10000; i++ {
+			var mac lorawan.EUI64
+			binary.BigEndian.PutUint64(mac[:], uint64(0x0102030400000000+i))
+			macs = append(macs, mac)
+		}
+
+		Convey("Then the rollout bucket is stable", func() {
+			So(RolloutBucket(macs[0]), ShouldEqual, RolloutBucket(macs[0]))
+		})
+
+		Convey("Then a 5% rollout admits about 5% of the gateways", func() {
+			r := Rollout{Percentage: 5}
+			var admitted int
+			for _, mac := range macs {
+				if r.admits(RolloutBucket(mac), time.Now()) {
+					admitted++
+				}
+			}
+			So(admitted, ShouldBeBetween, 400, 600)
+		})
+
+		Convey("Then all gateways are admitted after the deadline", func() {
+			now := time.Now()
+			r := Rollout{Percentage: 0, Deadline: now}
+			for _, mac := range macs[:100] {
+				So(r.admits(RolloutBucket(mac), now), ShouldBeTrue)
+				So(r.admits(RolloutBucket(mac), now.Add(-time.Second)), ShouldBeFalse)
+			}
+		})
+	})
+
+	Convey("Given a set of rollout metadata tests", t, func() {
+		deadline := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+		tests := []struct {
+			Name    string
+			MD      metadata.MD
+			Rollout *Rollout
+			Error   bool
+		}{
+			{"no rollout", metadata.Pairs(), nil, false},
+			{"percentage", metadata.Pairs("rollout-percentage", "5"), &Rollout{Percentage: 5}, false},
+			{"percentage and deadline", metadata.Pairs("rollout-percentage", "5", "rollout-deadline", "2017-06-01T12:00:00Z"), &Rollout{Percentage: 5, Deadline: deadline}, false},
+			{"invalid percentage", metadata.Pairs("rollout-percentage", "101"), nil, true},
+			{"invalid deadline", metadata.Pairs("rollout-percentage", "5", "rollout-deadline", "tomorrow"), nil, true},
+		}
+
+		for _, test := range tests {
+			Convey("Testing: "+test.Name, func() {
+				r, err := rolloutFromMetadata(test.MD)
+				if test.Error {
+					So(err, ShouldNotBeNil)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(r, ShouldResemble, test.Rollout)
+			})
+		}
+	})
+
+	Convey("Given a Manager with a RolloutSource", t, func() {
+		mac := lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+		bucket := RolloutBucket(mac)
+		now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+
+		source := testRolloutSource{
+			resp: gw.GetConfigurationResponse{UpdatedAt: now.Format(time.RFC3339Nano)},
+		}
+		conf := GatewayConfiguration{
+			Radios: [radioCount]RadioConfig{{Enable: true, Freq: 868500000}},
+		}
+		restarter := testRestarter{}
+		m, err := New(mac,
+			WithConfigSource(&source),
+			WithPlanner(testPlanner{conf: &conf}),
+			WithWriter(testWriter{}),
+			WithRestarter(&restarter),
+			WithClock(&testClock{now: now}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("When the gateway bucket is not admitted", func() {
+			source.rollout = &Rollout{Percentage: bucket, Deadline: now.Add(time.Hour)}
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+
+			Convey("Then the configuration is not applied", func() {
+				So(restarter.restarts, ShouldEqual, 0)
+				So(m.LastPlan().Rollout, ShouldResemble, source.rollout)
+			})
+
+			Convey("Then a forced update is not applied", func() {
+				So(m.applyOnce(context.Background(), true), ShouldBeNil)
+				So(restarter.restarts, ShouldEqual, 0)
+			})
+
+			Convey("When the rollout percentage is increased", func() {
+				source.rollout.Percentage = bucket + 1
+				So(m.ApplyOnce(context.Background()), ShouldBeNil)
+
+				Convey("Then the configuration is applied", func() {
+					So(restarter.restarts, ShouldEqual, 1)
+				})
+			})
+		})
+
+		Convey("When an update is not admitted after applying the configuration", func() {
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+			So(restarter.restarts, ShouldEqual, 1)
+
+			source.resp.UpdatedAt = now.Add(time.Minute).Format(time.RFC3339Nano)
+			source.rollout = &Rollout{Percentage: bucket, Deadline: now.Add(time.Hour)}
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+			So(rolloutPending(mac), ShouldEqual, 1)
+
+			Convey("When the server returns to the applied configuration", func() {
+				source.resp.UpdatedAt = now.Format(time.RFC3339Nano)
+				So(m.ApplyOnce(context.Background()), ShouldBeNil)
+
+				Convey("Then the rollout is no longer pending", func() {
+					So(rolloutPending(mac), ShouldEqual, 0)
+					So(restarter.restarts, ShouldEqual, 1)
+				})
+			})
+		})
+
+		Convey("When the rollout deadline has passed", func() {
+			source.rollout = &Rollout{Percentage: 0, Deadline: now}
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+
+			Convey("Then the configuration is applied", func() {
+				So(restarter.restarts, ShouldEqual, 1)
+			})
+		})
+	})
+}
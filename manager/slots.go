@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// getMultiSFSlots returns the frequency of each multi-SF channel slot
+// (chan_multiSF_N) of the given SX1301_conf. The frequency of a disabled
+// slot is 0.
+func getMultiSFSlots(sx1301Conf map[string]interface{}) ([channelCount]int, error) {
+	var out [channelCount]int
+
+	for i := range out {
+		channel, ok := sx1301Conf[fmt.Sprintf("chan_multiSF_%d", i)].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if enable, _ := channel["enable"].(bool); !enable {
+			continue
+		}
+
+		radioIdx, _ := channel["radio"].(float64)
+		radio, ok := sx1301Conf[fmt.Sprintf("radio_%d", int(radioIdx))].(map[string]interface{})
+		if !ok {
+			return out, fmt.Errorf("chan_multiSF_%d: invalid radio: %g", i, radioIdx)
+		}
+		radioFreq, _ := radio["freq"].(float64)
+		ifFreq, _ := channel["if"].(float64)
+
+		out[i] = int(radioFreq) + int(ifFreq)
+	}
+
+	return out, nil
+}
+
+// loadMultiSFSlots returns the multi-SF channel slots of the given
+// (previously written) configuration file. When the file does not exist,
+// all slots are returned as free.
+func loadMultiSFSlots(filePath string) ([channelCount]int, error) {
+	conf, err := loadConfigFile(filePath)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return [channelCount]int{}, nil
+		}
+		return [channelCount]int{}, err
+	}
+	return getMultiSFSlots(conf.SX1301Conf)
+}
+
+// assignMultiSFSlots assigns the given multi-SF channels to the channel
+// slots. Channels present in the previous slots are kept in the same slot,
+// the other channels are assigned to the free slots in order.
+func assignMultiSFSlots(channels []MultiSFChannelConfig, previous [channelCount]int) ([channelCount]MultiSFChannelConfig, error) {
+	var out [channelCount]MultiSFChannelConfig
+
+	if len(channels) > channelCount {
+		return out, errors.New("exceeded maximum number of multi-SF channels")
+	}
+
+	// keep the channels in their previous slot
+	var remaining []MultiSFChannelConfig
+	for _, c := range channels {
+		kept := false
+		for i, freq := range previous {
+			if freq != 0 && freq == c.Freq && !out[i].Enable {
+				out[i] = c
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			remaining = append(remaining, c)
+		}
+	}
+
+	// fill the free slots
+	for _, c := range remaining {
+		for i := range out {
+			if !out[i].Enable {
+				out[i] = c
+				break
+			}
+		}
+	}
+
+	return out, nil
+}
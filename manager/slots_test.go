@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+func TestMultiSFSlots(t *testing.T) {
+	Convey("Given a previously written EU868 configuration", t, func() {
+		tempDir, err := ioutil.TempDir("", "test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+		for _, f := range []int32{868100000, 868300000, 868500000} {
+			resp.Channels = append(resp.Channels, &gw.Channel{
+				Modulation:    gw.Modulation_LORA,
+				Frequency:     f,
+				Bandwidth:     125,
+				SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+			})
+		}
+
+		writer := FileWriter{
+			BaseConfigFile:   "test/test.json",
+			OutputConfigFile: filepath.Join(tempDir, "out.json"),
+		}
+		p := DefaultPlanner{
+			BaseConfigFile:     "test/test.json",
+			PreviousConfigFile: writer.OutputConfigFile,
+		}
+
+		Convey("When no previous configuration file exists", func() {
+			Convey("Then the channels are assigned in order", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				for i, f := range []int{868100000, 868300000, 868500000} {
+					So(conf.MultiSFChannels[i].Freq, ShouldEqual, f)
+				}
+			})
+		})
+
+		conf, err := p.Plan(&resp)
+		So(err, ShouldBeNil)
+		So(writer.Write(context.Background(), lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}, conf), ShouldBeNil)
+
+		Convey("Then the slots of the written configuration are returned", func() {
+			slots, err := loadMultiSFSlots(writer.OutputConfigFile)
+			So(err, ShouldBeNil)
+			So(slots, ShouldResemble, [channelCount]int{868100000, 868300000, 868500000})
+		})
+
+		Convey("When a channel is inserted before the existing channels", func() {
+			resp.Channels = append([]*gw.Channel{{
+				Modulation:    gw.Modulation_LORA,
+				Frequency:     867900000,
+				Bandwidth:     125,
+				SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+			}}, resp.Channels...)
+
+			Convey("Then the existing channels keep their slot", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				for i, f := range []int{868100000, 868300000, 868500000, 867900000} {
+					So(conf.MultiSFChannels[i].Enable, ShouldBeTrue)
+					So(conf.MultiSFChannels[i].Freq, ShouldEqual, f)
+				}
+			})
+		})
+
+		Convey("When a channel is removed", func() {
+			resp.Channels = append(resp.Channels[:1], resp.Channels[2:]...)
+
+			Convey("Then its slot is freed and the other channels keep their slot", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.MultiSFChannels[0].Freq, ShouldEqual, 868100000)
+				So(conf.MultiSFChannels[1].Enable, ShouldBeFalse)
+				So(conf.MultiSFChannels[2].Freq, ShouldEqual, 868500000)
+
+				Convey("When a channel is added", func() {
+					So(writer.Write(context.Background(), lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}, conf), ShouldBeNil)
+					resp.Channels = append(resp.Channels, &gw.Channel{
+						Modulation:    gw.Modulation_LORA,
+						Frequency:     867100000,
+						Bandwidth:     125,
+						SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+					})
+
+					Convey("Then the freed slot is used", func() {
+						conf, err := p.Plan(&resp)
+						So(err, ShouldBeNil)
+						So(conf.MultiSFChannels[0].Freq, ShouldEqual, 868100000)
+						So(conf.MultiSFChannels[1].Freq, ShouldEqual, 867100000)
+						So(conf.MultiSFChannels[2].Freq, ShouldEqual, 868500000)
+					})
+				})
+			})
+		})
+	})
+}
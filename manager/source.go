@@ -0,0 +1,59 @@
+package manager
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrUnauthenticated is returned when the gateway-server rejected the
+// credentials of the client (e.g. an invalid or expired jwt token).
+var ErrUnauthenticated = errors.New("gateway-server rejected the client credentials (invalid or expired jwt token?)")
+
+// ConfigSource provides the channel-configuration of a gateway.
+type ConfigSource interface {
+	GetConfiguration(ctx context.Context, mac lorawan.EUI64) (*gw.GetConfigurationResponse, error)
+}
+
+// GatewayClientSource implements ConfigSource using the gateway-server API.
+type GatewayClientSource struct {
+	Client gw.GatewayClient
+}
+
+// GetConfiguration returns the configuration for the given gateway MAC.
+func (s GatewayClientSource) GetConfiguration(ctx context.Context, mac lorawan.EUI64) (*gw.GetConfigurationResponse, error) {
+	resp, _, err := s.GetConfigurationRollout(ctx, mac)
+	return resp, err
+}
+
+// GetConfigurationRollout returns the configuration for the given gateway
+// MAC and its rollout. The rollout is read from the rollout-percentage and
+// rollout-deadline (RFC3339) header metadata of the response.
+func (s GatewayClientSource) GetConfigurationRollout(ctx context.Context, mac lorawan.EUI64) (*gw.GetConfigurationResponse, *Rollout, error) {
+	var header metadata.MD
+	resp, err := s.Client.GetConfiguration(ctx, &gw.GetConfigurationRequest{
+		Mac: mac[:],
+	}, grpc.Header(&header))
+	if err != nil {
+		if grpc.Code(err) == codes.Unauthenticated {
+			log.WithFields(log.Fields{
+				"gw_mac": mac,
+				"error":  grpc.ErrorDesc(err),
+			}).Error("gateway-server authentication error")
+			return nil, nil, ErrUnauthenticated
+		}
+		return nil, nil, errors.Wrap(err, "get configuration error")
+	}
+
+	rollout, err := rolloutFromMetadata(header)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "get rollout error")
+	}
+
+	return resp, rollout, nil
+}
@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+)
+
+// allSpreadFactors defines the spread-factors enabled by default on the
+// multi-SF channels.
+var allSpreadFactors = []int{7, 8, 9, 10, 11, 12}
+
+// restrictedSpreadFactors returns the given spread-factors (sorted) when
+// these are a restriction of allSpreadFactors, else nil.
+func restrictedSpreadFactors(sfs []int) []int {
+	set := make(map[int]bool)
+	for _, sf := range sfs {
+		set[sf] = true
+	}
+
+	restricted := false
+	for _, sf := range allSpreadFactors {
+		if !set[sf] {
+			restricted = true
+		}
+	}
+	if !restricted {
+		return nil
+	}
+
+	out := make([]int, 0, len(set))
+	for sf := range set {
+		out = append(out, sf)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// sameSpreadFactors returns true when both lists contain the same set of
+// spread-factors (ignoring order and duplicates).
+func sameSpreadFactors(a, b []int) bool {
+	setA := make(map[int]bool)
+	for _, sf := range a {
+		setA[sf] = true
+	}
+	setB := make(map[int]bool)
+	for _, sf := range b {
+		setB[sf] = true
+		if !setA[sf] {
+			return false
+		}
+	}
+	return len(setA) == len(setB)
+}
+
+// mergeSpreadFactors merges the spread-factor restrictions of the multi-SF
+// channels into the given configuration and returns a warning for each
+// restriction that can not be honored by the packet-forwarder:
+//
+//   - the SX1301 packet-forwarder always enables all spread-factors
+//   - the SX1302 HAL supports a single spread-factor list for all multi-SF
+//     channels (chan_multiSF_All.spreading_factor_enable), the union of the
+//     spread-factors of all channels is used
+func mergeSpreadFactors(config configFile, conf GatewayConfiguration) []string {
+	var warnings []string
+	var restricted bool
+	union := make(map[int]bool)
+
+	for _, c := range conf.MultiSFChannels {
+		if !c.Enable {
+			continue
+		}
+
+		sfs := restrictedSpreadFactors(c.SpreadFactors)
+		if len(sfs) == 0 {
+			sfs = allSpreadFactors
+		} else {
+			restricted = true
+			if !config.isSX130x() {
+				warnings = append(warnings, fmt.Sprintf("the SX1301 packet-forwarder does not support restricting the spread-factors of channel %d Hz to %v, all spread-factors are enabled", c.Freq, c.SpreadFactors))
+			}
+		}
+		for _, sf := range sfs {
+			union[sf] = true
+		}
+	}
+
+	if !restricted || !config.isSX130x() {
+		return warnings
+	}
+
+	var sfs []int
+	for sf := range union {
+		sfs = append(sfs, sf)
+	}
+	sort.Ints(sfs)
+
+	for _, c := range conf.MultiSFChannels {
+		if c.Enable && len(c.SpreadFactors) != 0 && !sameSpreadFactors(c.SpreadFactors, sfs) {
+			warnings = append(warnings, fmt.Sprintf("the SX1302 HAL does not support per-channel spread-factors, channel %d Hz is restricted to %v but uses %v", c.Freq, c.SpreadFactors, sfs))
+		}
+	}
+
+	all, ok := config.SX1301Conf["chan_multiSF_All"].(map[string]interface{})
+	if !ok {
+		all = make(map[string]interface{})
+		config.SX1301Conf["chan_multiSF_All"] = all
+	}
+	all["spreading_factor_enable"] = sfs
+
+	return warnings
+}
@@ -0,0 +1,140 @@
+package manager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+
+	"github.com/brocaar/lorawan"
+)
+
+func TestSpreadFactors(t *testing.T) {
+	Convey("Then restrictedSpreadFactors only returns restricted spread-factors", t, func() {
+		So(restrictedSpreadFactors([]int{12, 11, 10, 9, 8, 7}), ShouldBeNil)
+		So(restrictedSpreadFactors([]int{10, 9, 8, 7}), ShouldResemble, []int{7, 8, 9, 10})
+	})
+
+	Convey("Then sameSpreadFactors compares the sets of spread-factors", t, func() {
+		So(sameSpreadFactors([]int{9, 8, 7}, []int{7, 8, 9}), ShouldBeTrue)
+		So(sameSpreadFactors([]int{7, 7, 8}, []int{7, 8}), ShouldBeTrue)
+		So(sameSpreadFactors([]int{7, 8, 10}, []int{7, 8, 9}), ShouldBeFalse)
+		So(sameSpreadFactors([]int{7, 8, 8}, []int{7, 8, 9}), ShouldBeFalse)
+	})
+
+	Convey("Given a planned configuration with unsorted and unrestricted spread-factors", t, func() {
+		conf := GatewayConfiguration{
+			MultiSFChannels: [channelCount]MultiSFChannelConfig{
+				{Enable: true, Freq: 902300000, SpreadFactors: []int{10, 9, 8, 7}},
+				{Enable: true, Freq: 902500000, SpreadFactors: []int{7, 8, 9, 9}},
+				{Enable: true, Freq: 902700000, SpreadFactors: []int{12, 11, 10, 9, 8, 7}},
+			},
+		}
+
+		Convey("Given an SX1301 base configuration", func() {
+			baseConf, err := loadConfigFile("test/test.json")
+			So(err, ShouldBeNil)
+
+			Convey("Then no warning is returned for the unrestricted channel", func() {
+				warnings := mergeSpreadFactors(baseConf, conf)
+				So(warnings, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("Given an SX1302 base configuration", func() {
+			baseConf, err := loadConfigFile("test/test.json")
+			So(err, ShouldBeNil)
+			baseConf.SX130xConf = baseConf.SX1301Conf
+
+			Convey("Then the spread-factor sets are compared", func() {
+				conf.MultiSFChannels[2].Enable = false
+				So(mergeSpreadFactors(baseConf, conf), ShouldResemble, []string{
+					"the SX1302 HAL does not support per-channel spread-factors, channel 902500000 Hz is restricted to [7 8 9 9] but uses [7 8 9 10]",
+				})
+			})
+		})
+	})
+
+	Convey("Given a planned configuration with restricted spread-factors", t, func() {
+		conf := GatewayConfiguration{
+			MultiSFChannels: [channelCount]MultiSFChannelConfig{
+				{Enable: true, Freq: 902300000, SpreadFactors: []int{7, 8, 9, 10}},
+				{Enable: true, Freq: 902500000, SpreadFactors: []int{7, 8, 9}},
+			},
+		}
+
+		Convey("Given an SX1301 base configuration", func() {
+			baseConf, err := loadConfigFile("test/test.json")
+			So(err, ShouldBeNil)
+
+			Convey("Then a warning is returned for each restricted channel", func() {
+				warnings := mergeSpreadFactors(baseConf, conf)
+				So(warnings, ShouldResemble, []string{
+					"the SX1301 packet-forwarder does not support restricting the spread-factors of channel 902300000 Hz to [7 8 9 10], all spread-factors are enabled",
+					"the SX1301 packet-forwarder does not support restricting the spread-factors of channel 902500000 Hz to [7 8 9], all spread-factors are enabled",
+				})
+				So(baseConf.SX1301Conf["chan_multiSF_All"], ShouldBeNil)
+			})
+		})
+
+		Convey("Given an SX1302 base configuration", func() {
+			baseConf, err := loadConfigFile("test/test.json")
+			So(err, ShouldBeNil)
+			baseConf.SX130xConf = baseConf.SX1301Conf
+
+			Convey("Then the union of the spread-factors is set for all channels", func() {
+				warnings := mergeSpreadFactors(baseConf, conf)
+				So(warnings, ShouldResemble, []string{
+					"the SX1302 HAL does not support per-channel spread-factors, channel 902500000 Hz is restricted to [7 8 9] but uses [7 8 9 10]",
+				})
+				So(baseConf.SX130xConf["chan_multiSF_All"], ShouldResemble, map[string]interface{}{
+					"spreading_factor_enable": []int{7, 8, 9, 10},
+				})
+			})
+		})
+	})
+
+	Convey("Given an SX1302 base configuration file", t, func() {
+		tempDir, err := ioutil.TempDir("", "test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+
+		baseConf, err := loadConfigFile("test/test.json")
+		So(err, ShouldBeNil)
+		b, err := json.Marshal(map[string]interface{}{
+			"SX130x_conf":  baseConf.SX1301Conf,
+			"gateway_conf": baseConf.GatewayConf,
+		})
+		So(err, ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(tempDir, "base.json"), b, 0644), ShouldBeNil)
+
+		Convey("When writing a configuration with restricted spread-factors", func() {
+			w := FileWriter{
+				BaseConfigFile:   filepath.Join(tempDir, "base.json"),
+				OutputConfigFile: filepath.Join(tempDir, "out.json"),
+			}
+			So(w.Write(context.Background(), lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8}, GatewayConfiguration{
+				MultiSFChannels: [channelCount]MultiSFChannelConfig{
+					{Enable: true, Freq: 902300000, SpreadFactors: []int{7, 8, 9, 10}},
+				},
+			}), ShouldBeNil)
+
+			Convey("Then the output is written in the SX1302 format with the spread-factors", func() {
+				out, err := loadConfigFile(filepath.Join(tempDir, "out.json"))
+				So(err, ShouldBeNil)
+				So(out.isSX130x(), ShouldBeTrue)
+				So(out.SX130xConf["chan_multiSF_All"], ShouldResemble, map[string]interface{}{
+					"spreading_factor_enable": []interface{}{float64(7), float64(8), float64(9), float64(10)},
+				})
+
+				b, err := ioutil.ReadFile(filepath.Join(tempDir, "out.json"))
+				So(err, ShouldBeNil)
+				So(string(b), ShouldNotContainSubstring, "SX1301_conf")
+			})
+		})
+	})
+}
@@ -0,0 +1,277 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// txLUTCount defines the max. number of tx_lut entries supported by the
+// SX1301 HAL.
+const txLUTCount = 16
+
+// TXConfig contains the TX configuration of the concentrator.
+type TXConfig struct {
+	// KeepRadios keeps the radio TX settings (tx_enable, tx_freq_min and
+	// tx_freq_max) of the base configuration file, e.g. when only the
+	// antenna gain or max. EIRP is configured. When set, Enable, Radio,
+	// FreqMin and FreqMax are ignored.
+	KeepRadios bool
+
+	// Enable enables TX on the TX radio. When false, TX is disabled on all
+	// radios (e.g. for receive-only gateways).
+	Enable bool
+
+	// Radio defines the radio used for TX.
+	Radio int
+
+	// FreqMin and FreqMax define the allowed TX frequency range (Hz). Both
+	// must be set together. When left 0, the range of the base
+	// configuration file is used.
+	FreqMin int
+	FreqMax int
+
+	// MaxEIRP defines the max. TX power (EIRP, dBm). When set, the
+	// tx_lut entries of the base configuration file exceeding the resulting
+	// max. conducted power are clamped to the highest entry within this
+	// power. When left 0, the max. EIRP of the band is used (see
+	// DefaultPlanner) or when no band is set, the tx_lut of the base
+	// configuration file.
+	MaxEIRP int
+
+	// AntennaGain (dBi) and CableLoss (dB) define the gain of the antenna
+	// and the loss of the cable between the concentrator and the antenna.
+	// When one of them is set, the antenna_gain is set to the antenna gain
+	// minus the cable loss. When both are left 0, the antenna_gain of the
+	// base configuration file is used.
+	AntennaGain int
+	CableLoss   int
+}
+
+// validate validates the TX configuration.
+func (c TXConfig) validate() error {
+	if c.KeepRadios {
+		return c.validateGain()
+	}
+	if c.Radio < 0 || c.Radio >= radioCount {
+		return fmt.Errorf("invalid tx radio: %d", c.Radio)
+	}
+	if (c.FreqMin == 0) != (c.FreqMax == 0) {
+		return fmt.Errorf("tx frequency min (%d) and max (%d) must be set together", c.FreqMin, c.FreqMax)
+	}
+	if c.FreqMin != 0 && c.FreqMin >= c.FreqMax {
+		return fmt.Errorf("tx frequency min (%d) must be less than max (%d)", c.FreqMin, c.FreqMax)
+	}
+	return c.validateGain()
+}
+
+// validateGain validates the antenna gain and cable loss.
+func (c TXConfig) validateGain() error {
+	if c.CableLoss < 0 {
+		return fmt.Errorf("cable loss must not be negative: %d", c.CableLoss)
+	}
+	return nil
+}
+
+// txLUTEntry contains a single tx_lut (SX1301) or tx_gain_lut (SX1302)
+// entry of the base configuration file. The gain settings are board
+// specific (calibration) and must be preserved.
+type txLUTEntry struct {
+	rfPower float64
+	values  map[string]interface{}
+}
+
+// mergeTXConfig merges the TX configuration into the given SX1301_conf (or
+// SX130x_conf). The radio TX settings (unless KeepRadios is set) and antenna
+// gain are updated and when a max. EIRP is set, the tx_lut entries (or the
+// tx_gain_lut entries of the TX radios) exceeding the max. conducted power
+// are clamped to the highest calibrated entry of the base configuration
+// within this power.
+func mergeTXConfig(sx1301Conf map[string]interface{}, tx TXConfig) error {
+	if err := tx.validate(); err != nil {
+		return err
+	}
+
+	// update radios
+	for i := 0; i < radioCount && !tx.KeepRadios; i++ {
+		radio, ok := sx1301Conf[fmt.Sprintf("radio_%d", i)].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected radio_%d to be of type map[string]interface{}, got %T", i, sx1301Conf[fmt.Sprintf("radio_%d", i)])
+		}
+
+		radio["tx_enable"] = tx.Enable && i == tx.Radio
+		if i == tx.Radio && tx.FreqMin != 0 {
+			radio["tx_freq_min"] = tx.FreqMin
+			radio["tx_freq_max"] = tx.FreqMax
+		}
+	}
+
+	// the packet-forwarder compensates the requested (EIRP) power with the
+	// antenna gain, the cable loss reduces the effective antenna gain
+	var antennaGain float64
+	if v, ok := sx1301Conf["antenna_gain"].(float64); ok {
+		antennaGain = v
+	}
+	if tx.AntennaGain != 0 || tx.CableLoss != 0 {
+		sx1301Conf["antenna_gain"] = tx.AntennaGain - tx.CableLoss
+		antennaGain = float64(tx.AntennaGain - tx.CableLoss)
+	}
+
+	if (!tx.KeepRadios && !tx.Enable) || tx.MaxEIRP == 0 {
+		return nil
+	}
+
+	maxRFPower := float64(tx.MaxEIRP) - antennaGain
+
+	entries, err := getTXLUT(sx1301Conf)
+	if err != nil {
+		return errors.Wrap(err, "get tx_lut error")
+	}
+	if len(entries) != 0 {
+		entries, err = clampTXLUT(entries, maxRFPower)
+		if err != nil {
+			return errors.Wrap(err, "clamp tx_lut error")
+		}
+
+		for i := 0; i < txLUTCount; i++ {
+			delete(sx1301Conf, fmt.Sprintf("tx_lut_%d", i))
+		}
+		for i, e := range entries {
+			sx1301Conf[fmt.Sprintf("tx_lut_%d", i)] = e.values
+		}
+		return nil
+	}
+
+	// SX1302 based configuration files do not contain the tx_lut entries,
+	// but a tx_gain_lut for each TX enabled radio
+	var clamped bool
+	for i := 0; i < radioCount; i++ {
+		radio, ok := sx1301Conf[fmt.Sprintf("radio_%d", i)].(map[string]interface{})
+		if !ok || radio["tx_enable"] != true {
+			continue
+		}
+
+		entries, err := getTXGainLUT(radio)
+		if err != nil {
+			return errors.Wrapf(err, "get radio_%d tx_gain_lut error", i)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		entries, err = clampTXLUT(entries, maxRFPower)
+		if err != nil {
+			return errors.Wrapf(err, "clamp radio_%d tx_gain_lut error", i)
+		}
+
+		lut := make([]interface{}, len(entries))
+		for j, e := range entries {
+			lut[j] = e.values
+		}
+		radio["tx_gain_lut"] = lut
+		clamped = true
+	}
+	if !clamped {
+		return errors.New("base configuration does not contain tx_lut or tx_gain_lut entries")
+	}
+
+	return nil
+}
+
+// clampTXLUT clamps the given entries (sorted by rf_power) exceeding the
+// max. rf power to the highest entry within this power.
+//
+// The gain settings of an entry are calibrated for its rf_power and can not
+// be scaled. Entries exceeding the max. power are therefore clamped to the
+// highest calibrated entry within the max. power, so that the HAL selects
+// this entry for all higher power requests and the number of entries is
+// preserved.
+func clampTXLUT(entries []txLUTEntry, maxRFPower float64) ([]txLUTEntry, error) {
+	clamp := -1
+	for i, e := range entries {
+		if e.rfPower <= maxRFPower {
+			clamp = i
+		}
+	}
+	if clamp == -1 {
+		return nil, fmt.Errorf("no entry within the max. rf power of %g dBm", maxRFPower)
+	}
+
+	out := make([]txLUTEntry, len(entries))
+	for i, e := range entries {
+		if i > clamp {
+			e = entries[clamp]
+		}
+		values := make(map[string]interface{}, len(e.values))
+		for k, v := range e.values {
+			values[k] = v
+		}
+		out[i] = txLUTEntry{rfPower: e.rfPower, values: values}
+	}
+
+	return out, nil
+}
+
+// getTXLUT returns the tx_lut entries of the given SX1301_conf, sorted by
+// rf_power.
+func getTXLUT(sx1301Conf map[string]interface{}) ([]txLUTEntry, error) {
+	var out []txLUTEntry
+
+	for i := 0; i < txLUTCount; i++ {
+		v, ok := sx1301Conf[fmt.Sprintf("tx_lut_%d", i)]
+		if !ok {
+			continue
+		}
+
+		e, err := getTXLUTEntry(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tx_lut_%d error", i)
+		}
+		out = append(out, e)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].rfPower < out[j].rfPower })
+
+	return out, nil
+}
+
+// getTXGainLUT returns the tx_gain_lut entries of the given SX130x_conf
+// radio, sorted by rf_power.
+func getTXGainLUT(radio map[string]interface{}) ([]txLUTEntry, error) {
+	v, ok := radio["tx_gain_lut"]
+	if !ok {
+		return nil, nil
+	}
+	lut, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected tx_gain_lut to be of type []interface{}, got %T", v)
+	}
+
+	var out []txLUTEntry
+	for i, v := range lut {
+		e, err := getTXLUTEntry(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "entry %d error", i)
+		}
+		out = append(out, e)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].rfPower < out[j].rfPower })
+
+	return out, nil
+}
+
+// getTXLUTEntry returns the tx_lut (or tx_gain_lut) entry for the given
+// value.
+func getTXLUTEntry(v interface{}) (txLUTEntry, error) {
+	values, ok := v.(map[string]interface{})
+	if !ok {
+		return txLUTEntry{}, fmt.Errorf("expected entry to be of type map[string]interface{}, got %T", v)
+	}
+	rfPower, ok := values["rf_power"].(float64)
+	if !ok {
+		return txLUTEntry{}, fmt.Errorf("expected rf_power to be of type float64, got %T", values["rf_power"])
+	}
+
+	return txLUTEntry{rfPower: rfPower, values: values}, nil
+}
@@ -0,0 +1,218 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan/band"
+)
+
+func TestMergeTXConfig(t *testing.T) {
+	Convey("Given the test base configuration", t, func() {
+		conf, err := loadConfigFile("test/test.json")
+		So(err, ShouldBeNil)
+
+		rfPowers := func() []float64 {
+			var out []float64
+			for i := 0; i < txLUTCount; i++ {
+				if lut, ok := conf.SX1301Conf[fmt.Sprintf("tx_lut_%d", i)].(map[string]interface{}); ok {
+					out = append(out, lut["rf_power"].(float64))
+				}
+			}
+			return out
+		}
+		radio := func(i int) map[string]interface{} {
+			return conf.SX1301Conf[fmt.Sprintf("radio_%d", i)].(map[string]interface{})
+		}
+
+		Convey("When merging a TX configuration with frequency range and max. EIRP", func() {
+			err := mergeTXConfig(conf.SX1301Conf, TXConfig{
+				Enable:  true,
+				Radio:   1,
+				FreqMin: 863000000,
+				FreqMax: 870000000,
+				MaxEIRP: 14,
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then only the TX radio has TX enabled with the frequency range", func() {
+				So(radio(0)["tx_enable"], ShouldEqual, false)
+				So(radio(1)["tx_enable"], ShouldEqual, true)
+				So(radio(1)["tx_freq_min"], ShouldEqual, 863000000)
+				So(radio(1)["tx_freq_max"], ShouldEqual, 870000000)
+			})
+
+			Convey("Then the tx_lut entries exceeding the max. EIRP are clamped", func() {
+				So(rfPowers(), ShouldResemble, []float64{-6, -3, 0, 3, 6, 10, 11, 12, 13, 14, 14, 14})
+			})
+
+			Convey("Then the clamped entries use the calibrated gain settings of the highest entry within the max. EIRP", func() {
+				So(conf.SX1301Conf["tx_lut_11"], ShouldResemble, conf.SX1301Conf["tx_lut_9"])
+			})
+
+			Convey("Then the calibrated gain settings are preserved", func() {
+				lut := conf.SX1301Conf["tx_lut_9"].(map[string]interface{})
+				So(lut["pa_gain"], ShouldEqual, 2)
+				So(lut["mix_gain"], ShouldEqual, 10)
+			})
+		})
+
+		Convey("When the base configuration has an antenna gain", func() {
+			conf.SX1301Conf["antenna_gain"] = float64(3)
+			err := mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, MaxEIRP: 14})
+			So(err, ShouldBeNil)
+
+			Convey("Then the antenna gain is taken into account", func() {
+				So(rfPowers(), ShouldResemble, []float64{-6, -3, 0, 3, 6, 10, 11, 11, 11, 11, 11, 11})
+			})
+		})
+
+		Convey("When merging a TX configuration with antenna gain and cable loss", func() {
+			err := mergeTXConfig(conf.SX1301Conf, TXConfig{
+				Enable:      true,
+				MaxEIRP:     16,
+				AntennaGain: 6,
+				CableLoss:   2,
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then the antenna_gain is set to the effective antenna gain", func() {
+				So(conf.SX1301Conf["antenna_gain"], ShouldEqual, 4)
+			})
+
+			Convey("Then the tx_lut is clamped to the max. conducted power", func() {
+				So(rfPowers(), ShouldResemble, []float64{-6, -3, 0, 3, 6, 10, 11, 12, 12, 12, 12, 12})
+			})
+		})
+
+		Convey("When only the antenna gain is configured", func() {
+			radio0, radio1 := radio(0)["tx_enable"], radio(1)["tx_enable"]
+			err := mergeTXConfig(conf.SX1301Conf, TXConfig{KeepRadios: true, AntennaGain: 6})
+			So(err, ShouldBeNil)
+
+			Convey("Then the radio TX settings of the base configuration are kept", func() {
+				So(radio(0)["tx_enable"], ShouldEqual, radio0)
+				So(radio(1)["tx_enable"], ShouldEqual, radio1)
+				So(conf.SX1301Conf["antenna_gain"], ShouldEqual, 6)
+			})
+		})
+
+		Convey("When TX is disabled", func() {
+			err := mergeTXConfig(conf.SX1301Conf, TXConfig{MaxEIRP: 14})
+			So(err, ShouldBeNil)
+
+			Convey("Then TX is disabled on all radios and the tx_lut is kept", func() {
+				So(radio(0)["tx_enable"], ShouldEqual, false)
+				So(radio(1)["tx_enable"], ShouldEqual, false)
+				So(rfPowers(), ShouldHaveLength, 12)
+			})
+		})
+
+		Convey("Then a max. EIRP below all tx_lut entries returns an error", func() {
+			So(mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, MaxEIRP: -10}), ShouldNotBeNil)
+		})
+
+		Convey("Then an invalid TX configuration returns an error", func() {
+			So(mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, Radio: 2}), ShouldNotBeNil)
+			So(mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, FreqMin: 870000000, FreqMax: 863000000}), ShouldNotBeNil)
+		})
+
+		Convey("Then a half-set TX frequency range returns an error", func() {
+			So(mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, FreqMax: 870000000}), ShouldNotBeNil)
+			So(mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, FreqMin: 863000000}), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given the SX1302 test base configuration", t, func() {
+		conf, err := loadConfigFile("test/test_sx1302.json")
+		So(err, ShouldBeNil)
+
+		radio := func(i int) map[string]interface{} {
+			return conf.SX1301Conf[fmt.Sprintf("radio_%d", i)].(map[string]interface{})
+		}
+		rfPowers := func(i int) []float64 {
+			var out []float64
+			for _, v := range radio(i)["tx_gain_lut"].([]interface{}) {
+				out = append(out, v.(map[string]interface{})["rf_power"].(float64))
+			}
+			return out
+		}
+
+		Convey("When merging a TX configuration with max. EIRP", func() {
+			err := mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, MaxEIRP: 16})
+			So(err, ShouldBeNil)
+
+			Convey("Then the tx_gain_lut entries of the TX radio exceeding the max. EIRP are clamped", func() {
+				So(rfPowers(0), ShouldResemble, []float64{12, 14, 16, 16, 16, 16})
+			})
+
+			Convey("Then the clamped entries use the calibrated gain settings of the highest entry within the max. EIRP", func() {
+				lut := radio(0)["tx_gain_lut"].([]interface{})
+				So(lut[5], ShouldResemble, lut[2])
+				So(lut[5].(map[string]interface{})["pwr_idx"], ShouldEqual, 17)
+			})
+		})
+
+		Convey("Then a max. EIRP below all tx_gain_lut entries returns an error", func() {
+			So(mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, MaxEIRP: 10}), ShouldNotBeNil)
+		})
+
+		Convey("Then a TX radio without tx_gain_lut returns an error", func() {
+			So(mergeTXConfig(conf.SX1301Conf, TXConfig{Enable: true, Radio: 1, MaxEIRP: 16}), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a DefaultPlanner with TX configuration", t, func() {
+		p := DefaultPlanner{TX: &TXConfig{Enable: true, MaxEIRP: 14}}
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+
+		Convey("Then the planned configuration contains the TX configuration", func() {
+			conf, err := p.Plan(&resp)
+			So(err, ShouldBeNil)
+			So(conf.TX, ShouldResemble, &TXConfig{Enable: true, MaxEIRP: 14})
+		})
+
+		Convey("Then an invalid TX radio returns an error", func() {
+			p.TX.Radio = 3
+			_, err := p.Plan(&resp)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Given a band and no max. EIRP", func() {
+			p.Band = band.EU_863_870
+			p.TX.MaxEIRP = 0
+
+			Convey("Then the highest max. EIRP of the band sub-bands is used", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.TX.MaxEIRP, ShouldEqual, 27)
+			})
+
+			Convey("Then the max. EIRP of the sub-bands within the TX frequency range is used", func() {
+				maxEIRP, err := getBandMaxEIRP(band.EU_863_870, 863000000, 868600000)
+				So(err, ShouldBeNil)
+				So(maxEIRP, ShouldEqual, 16)
+
+				_, err = getBandMaxEIRP(band.EU_863_870, 902000000, 928000000)
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("Then the max. EIRP of a band without sub-bands is used", func() {
+				p.Band = band.US_902_928
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.TX.MaxEIRP, ShouldEqual, 30)
+			})
+
+			Convey("Then an unknown band returns an error", func() {
+				p.Band = "EU_868"
+				_, err := p.Plan(&resp)
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
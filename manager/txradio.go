@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/brocaar/lorawan"
+	"github.com/brocaar/lorawan/band"
+	"github.com/pkg/errors"
+)
+
+// getTXRadio returns the radio used for TX. When the TX radio is
+// configured, this is the configured TX radio, else the radio with TX
+// enabled in the base configuration file. It returns -1 when TX is
+// disabled or unknown.
+func getTXRadio(tx *TXConfig, hw [radioCount]RadioHardwareConfig) int {
+	if tx != nil && !tx.KeepRadios {
+		if !tx.Enable {
+			return -1
+		}
+		return tx.Radio
+	}
+
+	for i, r := range hw {
+		if r.tx {
+			return i
+		}
+	}
+	return -1
+}
+
+// enableRequiredRadios makes sure the radio providing the clock and the TX
+// radio are enabled, also when no channels are planned on these radios.
+// These radios are tuned to the center frequency of an other enabled radio,
+// within their allowed frequency range.
+func enableRequiredRadios(conf *GatewayConfiguration, hw [radioCount]RadioHardwareConfig, radios ...int) {
+	var freq int
+	for _, r := range conf.Radios {
+		if r.Enable {
+			freq = r.Freq
+			break
+		}
+	}
+	if freq == 0 {
+		return
+	}
+
+	for _, i := range radios {
+		if i < 0 || conf.Radios[i].Enable {
+			continue
+		}
+		conf.Radios[i].Enable = true
+		conf.Radios[i].Freq = hw[i].clampFreq(freq)
+	}
+}
+
+// getDownlinkFrequencies returns the frequencies used for downlink by the
+// planned configuration: the RX1 frequencies of the planned channels, the
+// RX2 frequency of the band and the beacon frequencies. Channels for which
+// the band does not define the RX1 frequency are skipped.
+func getDownlinkFrequencies(name band.Name, conf GatewayConfiguration) ([]int, error) {
+	bandConfig, err := band.GetConfig(name, false, lorawan.DwellTimeNoLimit)
+	if err != nil {
+		return nil, errors.Wrap(err, "get band config error")
+	}
+
+	freqs := map[int]struct{}{
+		bandConfig.RX2Frequency: struct{}{},
+	}
+
+	var uplink []int
+	for _, c := range conf.MultiSFChannels {
+		if c.Enable {
+			uplink = append(uplink, c.Freq)
+		}
+	}
+	if conf.LoRaSTDChannelConfig.Enable {
+		uplink = append(uplink, conf.LoRaSTDChannelConfig.Freq)
+	}
+	if conf.FSKChannelConfig.Enable {
+		uplink = append(uplink, conf.FSKChannelConfig.Freq)
+	}
+	for _, f := range uplink {
+		if rx1, err := bandConfig.GetRX1Frequency(f); err == nil {
+			freqs[rx1] = struct{}{}
+		}
+	}
+
+	if b := conf.Beacon; b != nil && b.Period != 0 {
+		freqs[b.Freq] = struct{}{}
+		for i := 1; i < b.FreqNb; i++ {
+			freqs[b.Freq+i*b.FreqStep] = struct{}{}
+		}
+	}
+
+	var out []int
+	for f := range freqs {
+		out = append(out, f)
+	}
+	sort.Ints(out)
+
+	return out, nil
+}
+
+// placeTXRadio returns the radio used for TX, making sure the downlink
+// frequencies of the planned configuration are reachable by it. When the
+// TX radio is configured (TXConfig), only this radio is validated. Else the
+// TX radio of the base configuration file is preferred and when it can not
+// reach the downlink frequencies, TX is moved to the first other radio
+// which TX frequency range (tx_freq_min and tx_freq_max) covers these.
+func placeTXRadio(name band.Name, conf *GatewayConfiguration, hw [radioCount]RadioHardwareConfig, txRadio int) (int, error) {
+	freqs, err := getDownlinkFrequencies(name, *conf)
+	if err != nil {
+		return txRadio, errors.Wrap(err, "get downlink frequencies error")
+	}
+
+	err = checkTXRadio(freqs, *conf, hw, txRadio)
+	if err == nil || (conf.TX != nil && !conf.TX.KeepRadios) {
+		return txRadio, err
+	}
+
+	for i := range hw {
+		if i == txRadio || hw[i].txFreqMin == 0 || hw[i].txFreqMax == 0 {
+			continue
+		}
+		if checkTXRadio(freqs, *conf, hw, i) != nil {
+			continue
+		}
+
+		tx := TXConfig{}
+		if conf.TX != nil {
+			tx = *conf.TX
+		}
+		tx.KeepRadios = false
+		tx.Enable = true
+		tx.Radio = i
+		conf.TX = &tx
+		return i, nil
+	}
+
+	return txRadio, err
+}
+
+// checkTXRadio validates that the given downlink frequencies are reachable
+// by the TX radio, which must be within the allowed frequency range of the
+// radio and within the TX frequency range.
+func checkTXRadio(freqs []int, conf GatewayConfiguration, hw [radioCount]RadioHardwareConfig, txRadio int) error {
+	txFreqMin, txFreqMax := hw[txRadio].txFreqMin, hw[txRadio].txFreqMax
+	if conf.TX != nil && !conf.TX.KeepRadios && conf.TX.FreqMin != 0 {
+		txFreqMin, txFreqMax = conf.TX.FreqMin, conf.TX.FreqMax
+	}
+
+	for _, f := range freqs {
+		if !hw[txRadio].covers(f, f) {
+			return fmt.Errorf("downlink frequency %d Hz is outside the frequency range of tx radio %d", f, txRadio)
+		}
+		if (txFreqMin != 0 || txFreqMax != 0) && (f < txFreqMin || f > txFreqMax) {
+			return fmt.Errorf("downlink frequency %d Hz is outside the tx frequency range (%d - %d Hz) of tx radio %d", f, txFreqMin, txFreqMax, txRadio)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan/band"
+)
+
+func TestTXRadio(t *testing.T) {
+	Convey("Given an EU868 configuration response", t, func() {
+		resp := gw.GetConfigurationResponse{UpdatedAt: time.Now().Format(time.RFC3339Nano)}
+		for _, f := range []int32{868100000, 868300000, 868500000} {
+			resp.Channels = append(resp.Channels, &gw.Channel{
+				Modulation:    gw.Modulation_LORA,
+				Frequency:     f,
+				Bandwidth:     125,
+				SpreadFactors: []int32{7, 8, 9, 10, 11, 12},
+			})
+		}
+
+		Convey("Given a DefaultPlanner with the base configuration file (clksrc 1, tx radio 0)", func() {
+			p := DefaultPlanner{
+				Band:           band.EU_863_870,
+				BaseConfigFile: "test/test.json",
+			}
+
+			Convey("Then the channels are planned on the first radio and the clock source is enabled", func() {
+				conf, err := p.Plan(&resp)
+				So(err, ShouldBeNil)
+				So(conf.Radios, ShouldResemble, [radioCount]RadioConfig{
+					{Enable: true, Freq: 868500000},
+					{Enable: true, Freq: 868500000},
+				})
+				for _, c := range conf.MultiSFChannels[:3] {
+					So(c.Radio, ShouldEqual, 0)
+				}
+				So(conf.TX, ShouldBeNil)
+			})
+
+			Convey("When the tx frequency range does not cover the RX2 frequency", func() {
+				p.TX = &TXConfig{Enable: true, FreqMin: 863000000, FreqMax: 869000000}
+
+				Convey("Then an error is returned", func() {
+					_, err := p.Plan(&resp)
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldContainSubstring, "downlink frequency 869525000 Hz is outside the tx frequency range (863000000 - 869000000 Hz) of tx radio 0")
+				})
+			})
+
+			Convey("When tx is disabled", func() {
+				p.TX = &TXConfig{}
+
+				Convey("Then the clock source is enabled", func() {
+					conf, err := p.Plan(&resp)
+					So(err, ShouldBeNil)
+					So(conf.Radios[1], ShouldResemble, RadioConfig{Enable: true, Freq: 868500000})
+				})
+			})
+		})
+	})
+
+	Convey("Given radio hardware of which only the second radio can reach the RX2 frequency", t, func() {
+		hw := [radioCount]RadioHardwareConfig{
+			{tx: true, txFreqMin: 863000000, txFreqMax: 869000000},
+			{txFreqMin: 863000000, txFreqMax: 870000000},
+		}
+		conf := GatewayConfiguration{
+			MultiSFChannels: [channelCount]MultiSFChannelConfig{
+				{Enable: true, Freq: 868100000},
+			},
+		}
+
+		Convey("When the tx radio is read from the base configuration file", func() {
+			txRadio, err := placeTXRadio(band.EU_863_870, &conf, hw, 0)
+
+			Convey("Then tx is moved to the second radio", func() {
+				So(err, ShouldBeNil)
+				So(txRadio, ShouldEqual, 1)
+				So(conf.TX, ShouldResemble, &TXConfig{Enable: true, Radio: 1})
+			})
+		})
+
+		Convey("When only the antenna gain is configured", func() {
+			conf.TX = &TXConfig{KeepRadios: true, AntennaGain: 3}
+			txRadio, err := placeTXRadio(band.EU_863_870, &conf, hw, 0)
+
+			Convey("Then tx is moved to the second radio, keeping the antenna gain", func() {
+				So(err, ShouldBeNil)
+				So(txRadio, ShouldEqual, 1)
+				So(conf.TX, ShouldResemble, &TXConfig{Enable: true, Radio: 1, AntennaGain: 3})
+			})
+		})
+
+		Convey("When the tx radio is configured", func() {
+			conf.TX = &TXConfig{Enable: true, Radio: 0}
+			_, err := placeTXRadio(band.EU_863_870, &conf, hw, 0)
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "downlink frequency 869525000 Hz is outside the tx frequency range")
+			})
+		})
+	})
+
+	Convey("Given a US915 configuration response", t, func() {
+		resp := gw.GetConfigurationResponse{
+			UpdatedAt: time.Now().Format(time.RFC3339Nano),
+			Channels: []*gw.Channel{
+				{
+					Modulation:    gw.Modulation_LORA,
+					Frequency:     902300000,
+					Bandwidth:     125,
+					SpreadFactors: []int32{7, 8, 9, 10},
+				},
+				{
+					Modulation:    gw.Modulation_LORA,
+					Frequency:     902500000,
+					Bandwidth:     125,
+					SpreadFactors: []int32{7, 8, 9, 10},
+				},
+			},
+		}
+
+		Convey("Then the downlink frequencies are the RX1 and RX2 frequencies", func() {
+			conf, err := DefaultPlanner{}.Plan(&resp)
+			So(err, ShouldBeNil)
+			freqs, err := getDownlinkFrequencies(band.US_902_928, conf)
+			So(err, ShouldBeNil)
+			So(freqs, ShouldResemble, []int{923300000, 923900000})
+		})
+
+		Convey("Given the tx radio is limited to the uplink frequencies", func() {
+			p := DefaultPlanner{
+				Band: band.US_902_928,
+				TX:   &TXConfig{Enable: true, Radio: 1},
+				Radios: [radioCount]RadioHardwareConfig{
+					{},
+					{FreqMin: 902000000, FreqMax: 915000000},
+				},
+			}
+
+			Convey("Then an error is returned", func() {
+				_, err := p.Plan(&resp)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "downlink frequency 923300000 Hz is outside the frequency range of tx radio 1")
+			})
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package manager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronFields defines the fields of a maintenance window expression and
+// their allowed range.
+var cronFields = []struct {
+	name string
+	min  int
+	max  int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// MaintenanceWindow defines a cron-like maintenance window. The window is
+// open during every minute matching the expression, e.g. "* 2-4 * * 1-5"
+// is open from 02:00 until 04:59 on weekdays.
+type MaintenanceWindow struct {
+	expr     string
+	location *time.Location
+
+	// fields contains per cron field the allowed values.
+	fields [5]map[int]bool
+}
+
+// ParseMaintenanceWindow parses the given cron-like expression, consisting
+// of the minute, hour, day of month, month and day of week (0 = Sunday)
+// fields. Each field supports *, values, ranges (a-b), lists (a,b) and
+// steps (*/n or a-b/n). The expression is evaluated in the given location
+// (the local timezone when nil).
+func ParseMaintenanceWindow(expr string, loc *time.Location) (MaintenanceWindow, error) {
+	w := MaintenanceWindow{
+		expr:     expr,
+		location: loc,
+	}
+	if w.location == nil {
+		w.location = time.Local
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return w, fmt.Errorf("maintenance window %q: expected %d fields, got %d", expr, len(cronFields), len(parts))
+	}
+
+	for i, p := range parts {
+		values, err := parseCronField(p, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return w, errors.Wrapf(err, "maintenance window %q: %s", expr, cronFields[i].name)
+		}
+		w.fields[i] = values
+	}
+
+	return w, nil
+}
+
+// parseCronField returns the values matching the given cron field.
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	out := make(map[int]bool)
+
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i != -1 {
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step: %s", item)
+			}
+			item = item[:i]
+		}
+
+		from, to := min, max
+		if item != "*" {
+			var err error
+			bounds := strings.SplitN(item, "-", 2)
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value: %s", item)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value: %s", item)
+				}
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("value %s is outside the range %d - %d", item, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			out[v] = true
+		}
+	}
+
+	return out, nil
+}
+
+// Contains returns true when the maintenance window is open at the given
+// time.
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	t = t.In(w.location)
+	for i, v := range []int{t.Minute(), t.Hour(), t.Day(), int(t.Month()), int(t.Weekday())} {
+		if !w.fields[i][v] {
+			return false
+		}
+	}
+	return true
+}
+
+// String implements fmt.Stringer.
+func (w MaintenanceWindow) String() string {
+	return fmt.Sprintf("%s (%s)", w.expr, w.location)
+}
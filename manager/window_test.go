@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+
+	"github.com/brocaar/loraserver/api/gw"
+	"github.com/brocaar/lorawan"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	Convey("Given a set of maintenance window tests", t, func() {
+		amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+		So(err, ShouldBeNil)
+
+		tests := []struct {
+			Name     string
+			Expr     string
+			Location *time.Location
+			Time     time.Time
+			Contains bool
+		}{
+			{"any time", "* * * * *", time.UTC, time.Date(2017, 6, 1, 12, 34, 0, 0, time.UTC), true},
+			{"within hour range", "* 2-4 * * *", time.UTC, time.Date(2017, 6, 1, 4, 59, 0, 0, time.UTC), true},
+			{"outside hour range", "* 2-4 * * *", time.UTC, time.Date(2017, 6, 1, 5, 0, 0, 0, time.UTC), false},
+			{"weekday", "* 2-4 * * 1-5", time.UTC, time.Date(2017, 6, 2, 3, 0, 0, 0, time.UTC), true},
+			{"weekend", "* 2-4 * * 1-5", time.UTC, time.Date(2017, 6, 3, 3, 0, 0, 0, time.UTC), false},
+			{"list and step", "0,30 */6 * * *", time.UTC, time.Date(2017, 6, 1, 18, 30, 0, 0, time.UTC), true},
+			{"step not matching", "0,30 */6 * * *", time.UTC, time.Date(2017, 6, 1, 19, 30, 0, 0, time.UTC), false},
+			{"timezone", "* 2 * * *", amsterdam, time.Date(2017, 6, 1, 0, 15, 0, 0, time.UTC), true},
+			{"timezone not matching", "* 2 * * *", amsterdam, time.Date(2017, 6, 1, 2, 15, 0, 0, time.UTC), false},
+		}
+
+		for i, test := range tests {
+			Convey(fmt.Sprintf("Testing: %s [%d]", test.Name, i), func() {
+				w, err := ParseMaintenanceWindow(test.Expr, test.Location)
+				So(err, ShouldBeNil)
+				So(w.Contains(test.Time), ShouldEqual, test.Contains)
+			})
+		}
+
+		Convey("Then invalid expressions return an error", func() {
+			for _, expr := range []string{"* * * *", "60 * * * *", "* 4-2 * * *", "*/0 * * * *", "a * * * *", "* * 0 * *"} {
+				_, err := ParseMaintenanceWindow(expr, nil)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestManagerMaintenanceWindow(t *testing.T) {
+	Convey("Given a Manager with a maintenance window from 02:00 - 04:59 (UTC)", t, func() {
+		client := testGatewayClient{
+			GetConfigurationRequestChan: make(chan gw.GetConfigurationRequest, 100),
+			GetConfigurationResponse: gw.GetConfigurationResponse{
+				UpdatedAt: time.Now().Format(time.RFC3339Nano),
+			},
+		}
+		conf := GatewayConfiguration{
+			Radios: [radioCount]RadioConfig{{Enable: true, Freq: 868500000}},
+		}
+		restarter := testRestarter{}
+		clock := testClock{now: time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC), afterC: make(chan time.Time)}
+
+		window, err := ParseMaintenanceWindow("* 2-4 * * *", time.UTC)
+		So(err, ShouldBeNil)
+
+		m, err := New(lorawan.EUI64{1, 2, 3, 4, 5, 6, 7, 8},
+			WithConfigSource(GatewayClientSource{Client: &client}),
+			WithPlanner(testPlanner{conf: &conf}),
+			WithWriter(testWriter{}),
+			WithRestarter(&restarter),
+			WithMaintenanceWindows(window),
+			WithClock(&clock),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("When calling ApplyOnce outside the maintenance window", func() {
+			So(m.ApplyOnce(context.Background()), ShouldBeNil)
+
+			Convey("Then the configuration is staged", func() {
+				So(restarter.restarts, ShouldEqual, 0)
+				So(m.Staged(), ShouldNotBeNil)
+			})
+
+			Convey("When calling ApplyOnce within the maintenance window", func() {
+				clock.Set(time.Date(2017, 6, 2, 2, 0, 0, 0, time.UTC))
+				So(m.ApplyOnce(context.Background()), ShouldBeNil)
+
+				Convey("Then the staged configuration is applied", func() {
+					So(restarter.restarts, ShouldEqual, 1)
+					So(m.Staged(), ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("When calling ApplyNow outside the maintenance window", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- m.Run(ctx)
+			}()
+
+			<-client.GetConfigurationRequestChan
+			m.ApplyNow()
+			<-client.GetConfigurationRequestChan
+			cancel()
+			So(<-done, ShouldEqual, context.Canceled)
+
+			Convey("Then the configuration is applied", func() {
+				So(restarter.restarts, ShouldEqual, 1)
+				So(m.Staged(), ShouldBeNil)
+			})
+		})
+	})
+}